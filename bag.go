@@ -0,0 +1,251 @@
+package gord
+
+import "fmt"
+
+// Constructs a new `OrdBag` from the provided values, counting duplicates.
+func NewOrdBag(vals ...interface{}) *OrdBag {
+	var bag OrdBag
+	for _, val := range vals {
+		bag.Add(val)
+	}
+	return &bag
+}
+
+/*
+Ordered multiset: counts occurrences of each value, while keeping the order in
+which values were first added, like `LinkedSet`. Removing every occurrence of a
+value forgets its position; adding it again puts it at the end. Counting and
+lookups are O(1).
+
+Concurrency-unsafe; use `SyncOrdBag` for concurrent access. A zero value is
+ready to use, but should not be copied after the first mutation.
+*/
+type OrdBag struct {
+	set    LinkedSet
+	counts map[interface{}]int
+	total  int
+}
+
+var _ Set = (*OrdBag)(nil)
+
+// Value and its number of occurrences in an `OrdBag`.
+type BagEntry struct {
+	Val   interface{}
+	Count int
+}
+
+// Implement `fmt.Stringer`. Prints `val:count`.
+func (self BagEntry) String() string {
+	return fmt.Sprintf(`%v:%v`, self.Val, self.Count)
+}
+
+// Returns the number of distinct values. See `.Total` for the number of
+// occurrences.
+func (self *OrdBag) Len() int {
+	if self == nil {
+		return 0
+	}
+	return self.set.Len()
+}
+
+// Returns the number of occurrences of every value combined.
+func (self *OrdBag) Total() int {
+	if self == nil {
+		return 0
+	}
+	return self.total
+}
+
+// True if the value occurs at least once.
+func (self *OrdBag) Has(val interface{}) bool {
+	return self.Count(val) > 0
+}
+
+// Returns the number of occurrences of the value, or 0 if it's missing.
+func (self *OrdBag) Count(val interface{}) int {
+	if self == nil {
+		return 0
+	}
+	return self.counts[val]
+}
+
+// Adds one occurrence of the value. If the value was missing, appends it to
+// the end.
+func (self *OrdBag) Add(val interface{}) {
+	self.AddN(val, 1)
+}
+
+// Satisfy `Set`. Adds one occurrence of the value, and returns `true` if the
+// value was missing. Unlike in sets, adding a present value still increments
+// its count.
+func (self *OrdBag) Added(val interface{}) bool {
+	missing := !self.Has(val)
+	self.Add(val)
+	return missing
+}
+
+// Adds `count` occurrences of the value. If the value was missing, appends it
+// to the end. Does nothing if `count <= 0`.
+func (self *OrdBag) AddN(val interface{}, count int) {
+	if count <= 0 {
+		return
+	}
+	if self.counts == nil {
+		self.counts = map[interface{}]int{}
+	}
+	self.set.Add(val)
+	self.counts[val] += count
+	self.total += count
+}
+
+// Void version of `.Removed`.
+func (self *OrdBag) Remove(val interface{}) {
+	_ = self.Removed(val)
+}
+
+// If `bag.Has(val)`, removes one occurrence of the value, deleting it when the
+// count reaches 0, and returns `true`. Otherwise returns `false`.
+func (self *OrdBag) Removed(val interface{}) bool {
+	return self.RemovedN(val, 1) > 0
+}
+
+// Void version of `.RemovedN`.
+func (self *OrdBag) RemoveN(val interface{}, count int) {
+	_ = self.RemovedN(val, count)
+}
+
+// Removes up to `count` occurrences of the value, deleting it when the count
+// reaches 0. Returns the number of removed occurrences.
+func (self *OrdBag) RemovedN(val interface{}, count int) int {
+	prev := self.Count(val)
+	if prev == 0 || count <= 0 {
+		return 0
+	}
+	if count >= prev {
+		self.unset(val)
+		return prev
+	}
+	self.counts[val] = prev - count
+	self.total -= count
+	return count
+}
+
+// Void version of `.Deleted`.
+func (self *OrdBag) Delete(val interface{}) {
+	_ = self.Deleted(val)
+}
+
+// Satisfy `Set`. If `bag.Has(val)`, removes every occurrence of the value and
+// returns `true`. Otherwise returns `false`. See `.DeletedAll` for the count.
+func (self *OrdBag) Deleted(val interface{}) bool {
+	return self.DeletedAll(val) > 0
+}
+
+// Same as `.Deleted`, but returns the number of removed occurrences: the former
+// count of the value, or 0 if it was missing.
+func (self *OrdBag) DeletedAll(val interface{}) int {
+	count := self.Count(val)
+	if count > 0 {
+		self.unset(val)
+	}
+	return count
+}
+
+// Returns the distinct values in order of first addition.
+func (self *OrdBag) Values() []interface{} {
+	if self == nil {
+		return nil
+	}
+	return self.set.Values()
+}
+
+// Returns the values and their counts in order of first addition.
+func (self *OrdBag) Entries() []BagEntry {
+	out := make([]BagEntry, 0, self.Len())
+	self.Each(func(val interface{}, count int) {
+		out = append(out, BagEntry{val, count})
+	})
+	return out
+}
+
+// Calls the function for each value and its count in order of first addition,
+// without allocating. The function must not mutate the bag.
+func (self *OrdBag) Each(fun func(val interface{}, count int)) {
+	if self == nil {
+		return
+	}
+	self.set.eachWhile(func(val interface{}) bool {
+		fun(val, self.counts[val])
+		return true
+	})
+}
+
+/*
+Returns a new bag where each count is the sum of the counts in both bags.
+Values from this bag come first, in their order, followed by values found only
+in the other bag, in its order.
+*/
+func (self *OrdBag) Sum(other *OrdBag) *OrdBag {
+	var out OrdBag
+	self.sumInto(&out, other)
+	return &out
+}
+
+/*
+Returns a new bag where each count is the maximum of the counts in both bags.
+Ordered like `.Sum`.
+*/
+func (self *OrdBag) Union(other *OrdBag) *OrdBag {
+	var out OrdBag
+	self.unionInto(&out, other)
+	return &out
+}
+
+/*
+Returns a new bag where each count is the minimum of the counts in both bags,
+omitting values missing from either. Preserves the order of this bag.
+*/
+func (self *OrdBag) Intersection(other *OrdBag) *OrdBag {
+	var out OrdBag
+	self.intersectionInto(&out, other)
+	return &out
+}
+
+// Returns a copy of the bag, with the same values, counts and order.
+func (self *OrdBag) Copy() *OrdBag {
+	var out OrdBag
+	self.Each(out.AddN)
+	return &out
+}
+
+// Prints the entries, like a slice: `[a:2 b:1]`.
+func (self *OrdBag) String() string {
+	return fmt.Sprint(self.Entries())
+}
+
+func (self *OrdBag) sumInto(out, other *OrdBag) {
+	self.Each(out.AddN)
+	other.Each(out.AddN)
+}
+
+func (self *OrdBag) unionInto(out, other *OrdBag) {
+	self.Each(out.AddN)
+	other.Each(func(val interface{}, count int) {
+		out.AddN(val, count-out.Count(val))
+	})
+}
+
+func (self *OrdBag) intersectionInto(out, other *OrdBag) {
+	self.Each(func(val interface{}, count int) {
+		if other := other.Count(val); other < count {
+			count = other
+		}
+		out.AddN(val, count)
+	})
+}
+
+func (self *OrdBag) unset(val interface{}) {
+	self.total -= self.counts[val]
+	delete(self.counts, val)
+	self.set.Delete(val)
+}
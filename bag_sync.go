@@ -0,0 +1,221 @@
+package gord
+
+import "fmt"
+
+// Constructs a new `SyncOrdBag` from the provided values, counting duplicates.
+func NewSyncOrdBag(vals ...interface{}) *SyncOrdBag {
+	var bag SyncOrdBag
+	for _, val := range vals {
+		bag.bag.Add(val)
+	}
+	return &bag
+}
+
+// Concurrency-safe version of `OrdBag`. A zero value is ready to use, but
+// should never be copied. Uses a mutex.
+type SyncOrdBag struct {
+	lock statMutex
+	bag  OrdBag
+}
+
+var _ Set = (*SyncOrdBag)(nil)
+
+// Concurrency-safe version of `OrdBag.Len`.
+func (self *SyncOrdBag) Len() int {
+	if self == nil {
+		return 0
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.bag.Len()
+}
+
+// Concurrency-safe version of `OrdBag.Total`.
+func (self *SyncOrdBag) Total() int {
+	if self == nil {
+		return 0
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.bag.Total()
+}
+
+// Concurrency-safe version of `OrdBag.Has`.
+func (self *SyncOrdBag) Has(val interface{}) bool {
+	return self.Count(val) > 0
+}
+
+// Concurrency-safe version of `OrdBag.Count`.
+func (self *SyncOrdBag) Count(val interface{}) int {
+	if self == nil {
+		return 0
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.bag.Count(val)
+}
+
+// Concurrency-safe version of `OrdBag.Add`.
+func (self *SyncOrdBag) Add(val interface{}) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.bag.Add(val)
+}
+
+// Concurrency-safe version of `OrdBag.Added`.
+func (self *SyncOrdBag) Added(val interface{}) bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.bag.Added(val)
+}
+
+// Concurrency-safe version of `OrdBag.AddN`.
+func (self *SyncOrdBag) AddN(val interface{}, count int) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.bag.AddN(val, count)
+}
+
+// Concurrency-safe version of `OrdBag.Remove`.
+func (self *SyncOrdBag) Remove(val interface{}) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.bag.Remove(val)
+}
+
+// Concurrency-safe version of `OrdBag.Removed`.
+func (self *SyncOrdBag) Removed(val interface{}) bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.bag.Removed(val)
+}
+
+// Concurrency-safe version of `OrdBag.RemoveN`.
+func (self *SyncOrdBag) RemoveN(val interface{}, count int) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.bag.RemoveN(val, count)
+}
+
+// Concurrency-safe version of `OrdBag.RemovedN`.
+func (self *SyncOrdBag) RemovedN(val interface{}, count int) int {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.bag.RemovedN(val, count)
+}
+
+// Concurrency-safe version of `OrdBag.Delete`.
+func (self *SyncOrdBag) Delete(val interface{}) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.bag.Delete(val)
+}
+
+// Concurrency-safe version of `OrdBag.Deleted`.
+func (self *SyncOrdBag) Deleted(val interface{}) bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.bag.Deleted(val)
+}
+
+// Concurrency-safe version of `OrdBag.DeletedAll`.
+func (self *SyncOrdBag) DeletedAll(val interface{}) int {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.bag.DeletedAll(val)
+}
+
+// Concurrency-safe version of `OrdBag.Values`.
+func (self *SyncOrdBag) Values() []interface{} {
+	if self == nil {
+		return nil
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.bag.Values()
+}
+
+// Concurrency-safe version of `OrdBag.Entries`.
+func (self *SyncOrdBag) Entries() []BagEntry {
+	if self == nil {
+		return []BagEntry{}
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.bag.Entries()
+}
+
+// Concurrency-safe version of `OrdBag.Each`. Holds the lock while iterating;
+// the function must not call methods of the same `SyncOrdBag`, which would
+// deadlock.
+func (self *SyncOrdBag) Each(fun func(val interface{}, count int)) {
+	if self == nil {
+		return
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.bag.Each(fun)
+}
+
+// Concurrency-safe version of `OrdBag.Sum`. Copies the other bag first, and
+// never holds both locks at once.
+func (self *SyncOrdBag) Sum(other *SyncOrdBag) *SyncOrdBag {
+	return self.combine(other, (*OrdBag).sumInto)
+}
+
+// Concurrency-safe version of `OrdBag.Union`. Copies the other bag first, and
+// never holds both locks at once.
+func (self *SyncOrdBag) Union(other *SyncOrdBag) *SyncOrdBag {
+	return self.combine(other, (*OrdBag).unionInto)
+}
+
+// Concurrency-safe version of `OrdBag.Intersection`. Copies the other bag
+// first, and never holds both locks at once.
+func (self *SyncOrdBag) Intersection(other *SyncOrdBag) *SyncOrdBag {
+	return self.combine(other, (*OrdBag).intersectionInto)
+}
+
+// Concurrency-safe version of `OrdBag.Copy`.
+func (self *SyncOrdBag) Copy() *SyncOrdBag {
+	var out SyncOrdBag
+	self.View(func(bag *OrdBag) { bag.Each(out.bag.AddN) })
+	return &out
+}
+
+// Concurrency-safe version of `OrdBag.String`.
+func (self *SyncOrdBag) String() string {
+	return fmt.Sprint(self.Entries())
+}
+
+// Runs the function against the inner `OrdBag` under a single lock
+// acquisition, making multi-step sequences atomic. The function must not
+// retain the `*OrdBag` after returning, and must not call methods of the same
+// `SyncOrdBag`, which would deadlock.
+func (self *SyncOrdBag) Update(fun func(*OrdBag)) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	fun(&self.bag)
+}
+
+// Same as `.Update`, but for read-only access. The function must not mutate
+// the bag.
+func (self *SyncOrdBag) View(fun func(*OrdBag)) {
+	if self == nil {
+		fun(nil)
+		return
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	fun(&self.bag)
+}
+
+func (self *SyncOrdBag) combine(
+	other *SyncOrdBag, fun func(self, out, other *OrdBag),
+) *SyncOrdBag {
+	var snapshot *OrdBag
+	other.View(func(bag *OrdBag) { snapshot = bag.Copy() })
+
+	var out SyncOrdBag
+	self.View(func(bag *OrdBag) { fun(bag, &out.bag, snapshot) })
+	return &out
+}
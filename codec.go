@@ -0,0 +1,49 @@
+package gord
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Converts values to and from bytes, for `WalSet` and `StreamFormat`. Decoded
+// values must be comparable, like every set value.
+type Codec interface {
+	Encode(val interface{}) ([]byte, error)
+	Decode(src []byte) (interface{}, error)
+}
+
+/*
+Default `Codec` of `WalSet` and `StreamFormat`, encoding values as JSON.
+Decodes values like `json.Unmarshal` into `interface{}`: strings, booleans and
+`nil` round-trip exactly, but every number becomes a `float64`. Objects and
+arrays decode into maps and slices, which aren't comparable, and are rejected.
+For other value types, such as ints or structs, use a custom `Codec`.
+*/
+type JSONCodec struct{}
+
+// Satisfy `Codec`.
+func (JSONCodec) Encode(val interface{}) ([]byte, error) { return json.Marshal(val) }
+
+// Satisfy `Codec`.
+func (JSONCodec) Decode(src []byte) (interface{}, error) {
+	var val interface{}
+	err := json.Unmarshal(src, &val)
+	if err != nil {
+		return nil, err
+	}
+
+	if isJSONComposite(val) {
+		return nil, fmt.Errorf(`[gord] unable to decode %s: JSON objects and arrays aren't comparable`, src)
+	}
+	return val, nil
+}
+
+// Objects and arrays decoded by `json.Unmarshal` into `interface{}` are maps
+// and slices, which can't be set values.
+func isJSONComposite(val interface{}) bool {
+	switch val.(type) {
+	case map[string]interface{}, []interface{}:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package gord
+
+// Counts of mutating operations, reported by `CountingSet`. Only operations
+// that change the set are counted: re-adding a present value, or deleting a
+// missing one, counts as nothing.
+type OpStats struct {
+	// Values added via `.Add`, `.AddFirst`, `.AddLast` and their `-ed` versions.
+	Adds uint64
+
+	// Values removed via `.Delete` and `.Deleted`.
+	Deletes uint64
+
+	// Present values moved via `.AddFirst`, `.AddLast` and their `-ed`
+	// versions.
+	Moves uint64
+
+	// Values removed via `.PoppedFirst` and `.PoppedLast`.
+	Pops uint64
+}
+
+// Wraps the set, counting mutating operations. See `CountingSet`.
+func NewCountingSet(set OrdSet) *CountingSet {
+	return &CountingSet{OrdSet: set}
+}
+
+/*
+Wrapper that counts mutating operations on the inner set, for exporting as
+metrics. Counters are updated atomically, so `.Ops` and `.Stats` may be called
+concurrently with other methods; the wrapper is as concurrency-safe as the
+inner set. Operations performed directly on the inner set aren't counted.
+*/
+type CountingSet struct {
+	OrdSet
+
+	adds    atomicUint64
+	deletes atomicUint64
+	moves   atomicUint64
+	pops    atomicUint64
+}
+
+var _ OrdSet = (*CountingSet)(nil)
+
+// Returns the current operation counts.
+func (self *CountingSet) Ops() OpStats {
+	return OpStats{
+		Adds:    self.adds.load(),
+		Deletes: self.deletes.load(),
+		Moves:   self.moves.load(),
+		Pops:    self.pops.load(),
+	}
+}
+
+// Satisfy `StatsSet`. Includes the inner set's stats, if it implements
+// `StatsSet`, and the operation counts.
+func (self *CountingSet) Stats() Stats {
+	var out Stats
+	if set, ok := self.OrdSet.(StatsSet); ok {
+		out = set.Stats()
+	} else {
+		out.Len = self.Len()
+		out.Cap = out.Len
+	}
+	out.Ops = self.Ops()
+	return out
+}
+
+// Satisfy `OrdSet`.
+func (self *CountingSet) Add(val interface{}) { _ = self.Added(val) }
+
+// Satisfy `OrdSet`.
+func (self *CountingSet) Added(val interface{}) bool {
+	return self.counted(self.OrdSet.Added(val), &self.adds)
+}
+
+// Satisfy `OrdSet`.
+func (self *CountingSet) Delete(val interface{}) { _ = self.Deleted(val) }
+
+// Satisfy `OrdSet`.
+func (self *CountingSet) Deleted(val interface{}) bool {
+	return self.counted(self.OrdSet.Deleted(val), &self.deletes)
+}
+
+// Satisfy `OrdSet`.
+func (self *CountingSet) AddFirst(val interface{}) { _ = self.AddedFirst(val) }
+
+// Satisfy `OrdSet`. Counts an add or a move.
+func (self *CountingSet) AddedFirst(val interface{}) bool {
+	return self.addedOrMoved(self.OrdSet.AddedFirst(val))
+}
+
+// Satisfy `OrdSet`.
+func (self *CountingSet) AddLast(val interface{}) { _ = self.AddedLast(val) }
+
+// Satisfy `OrdSet`. Counts an add or a move.
+func (self *CountingSet) AddedLast(val interface{}) bool {
+	return self.addedOrMoved(self.OrdSet.AddedLast(val))
+}
+
+// Satisfy `OrdSet`.
+func (self *CountingSet) PoppedFirst() (interface{}, bool) {
+	val, ok := self.OrdSet.PoppedFirst()
+	return val, self.counted(ok, &self.pops)
+}
+
+// Satisfy `OrdSet`.
+func (self *CountingSet) PoppedLast() (interface{}, bool) {
+	val, ok := self.OrdSet.PoppedLast()
+	return val, self.counted(ok, &self.pops)
+}
+
+func (self *CountingSet) addedOrMoved(added bool) bool {
+	if added {
+		self.adds.add(1)
+	} else {
+		self.moves.add(1)
+	}
+	return added
+}
+
+func (*CountingSet) counted(ok bool, counter *atomicUint64) bool {
+	if ok {
+		counter.add(1)
+	}
+	return ok
+}
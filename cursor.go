@@ -0,0 +1,292 @@
+package gord
+
+import "container/list"
+
+/*
+Returns a cursor positioned before the first value, so that the first call to
+`.Next` moves it to the first value:
+
+	for cur := set.Cursor(); cur.Next(); {
+		val, _ := cur.Value()
+		if expired(val) {
+			cur.Remove()
+		}
+	}
+*/
+func (self *LinkedSet) Cursor() *Cursor {
+	return &Cursor{set: self}
+}
+
+// Returns a cursor positioned after the last value, so that the first call to
+// `.Prev` moves it to the last value.
+func (self *LinkedSet) CursorEnd() *Cursor {
+	return &Cursor{set: self, end: true}
+}
+
+// If `set.Has(val)`, returns a cursor positioned on that value and `true`.
+// Otherwise returns `(nil, false)`.
+func (self *LinkedSet) CursorAt(val interface{}) (*Cursor, bool) {
+	if self == nil || self.set[val] == nil {
+		return nil, false
+	}
+	return &Cursor{set: self, elem: self.set[val]}, true
+}
+
+/*
+Position in a `LinkedSet` that tolerates modifications of the set. A cursor is
+either on a value, or in a gap between two values (or before the first, or
+after the last), which is where it remains after removing or moving the
+current value via the cursor. `.Next` and `.Prev` from a gap move to the
+value on the corresponding side.
+
+Modifications of the set during the walk, via the cursor or otherwise, have the
+following effects:
+
+• Removing values other than the current one is always safe. If the cursor is
+in a gap and a value bordering it is removed, the cursor moves relative to the
+remaining border. If both borders were removed, the cursor loses its position.
+
+• If the current value is moved, the cursor moves with it.
+
+• If the current value is removed by other code rather than via the cursor, the
+cursor loses its position.
+
+• Values added elsewhere are visited if they end up ahead of the cursor.
+
+Once the cursor has lost its position, `.Value`, `.Next` and `.Prev` return
+`false`, and mutating methods do nothing.
+
+Concurrency-unsafe, like `LinkedSet`.
+*/
+type Cursor struct {
+	set *LinkedSet
+
+	// The current value's node, or nil when in a gap.
+	elem *list.Element
+
+	// When in a gap: the nodes bordering it. Nil `prev` means the start of
+	// the list, and nil `next` means the end; see `.atStart` and `.atEnd`.
+	prev *list.Element
+	next *list.Element
+	end  bool
+	lost bool
+}
+
+// If the cursor is on a value, returns the value and `true`. Otherwise returns
+// `(nil, false)`.
+func (self *Cursor) Value() (interface{}, bool) {
+	if !self.live(self.elem) {
+		return nil, false
+	}
+	return self.elem.Value, true
+}
+
+// Moves the cursor to the next value and returns `true`. If there are no more
+// values, moves it after the last value and returns `false`.
+func (self *Cursor) Next() bool {
+	if self.elem != nil {
+		if !self.live(self.elem) {
+			return self.lose()
+		}
+		return self.moveTo(self.elem.Next(), self.elem, true)
+	}
+
+	switch {
+	case self.lost || self.set == nil:
+		return false
+	case self.live(self.next):
+		return self.moveTo(self.next, nil, true)
+	case self.atStart():
+		return self.moveTo(self.set.ord.Front(), nil, true)
+	case self.live(self.prev):
+		return self.moveTo(self.prev.Next(), self.prev, true)
+	case self.next == nil:
+		return self.moveTo(nil, nil, true)
+	default:
+		return self.lose()
+	}
+}
+
+// Moves the cursor to the previous value and returns `true`. If there are no
+// more values, moves it before the first value and returns `false`.
+func (self *Cursor) Prev() bool {
+	if self.elem != nil {
+		if !self.live(self.elem) {
+			return self.lose()
+		}
+		return self.moveTo(self.elem.Prev(), self.elem, false)
+	}
+
+	switch {
+	case self.lost || self.set == nil:
+		return false
+	case self.live(self.prev):
+		return self.moveTo(self.prev, nil, false)
+	case self.atEnd():
+		return self.moveTo(self.set.ord.Back(), nil, false)
+	case self.live(self.next):
+		return self.moveTo(self.next.Prev(), self.next, false)
+	case self.prev == nil:
+		return self.moveTo(nil, nil, false)
+	default:
+		return self.lose()
+	}
+}
+
+// If the cursor is on a value, removes it from the set, leaves the cursor in
+// the gap where it was, and returns `true`. Otherwise returns `false`.
+func (self *Cursor) Remove() bool {
+	elem := self.gap()
+	if elem == nil {
+		return false
+	}
+	if debugMode {
+		defer self.set.debugWrite()()
+	}
+	self.set.removeElem(elem)
+	return true
+}
+
+// If the cursor is on a value, moves it to the first position, leaves the
+// cursor in the gap where it was, and returns `true`. `.Next` then continues
+// with the following value. Otherwise returns `false`.
+func (self *Cursor) MoveToFront() bool {
+	elem := self.gap()
+	if elem == nil {
+		return false
+	}
+	if debugMode {
+		defer self.set.debugWrite()()
+	}
+	self.set.ord.MoveToFront(elem)
+	self.set.version++
+	return true
+}
+
+// If the cursor is on a value, moves it to the last position, leaves the
+// cursor in the gap where it was, and returns `true`. Otherwise returns
+// `false`. A forward walk that moves every value to the back never ends.
+func (self *Cursor) MoveToBack() bool {
+	elem := self.gap()
+	if elem == nil {
+		return false
+	}
+	if debugMode {
+		defer self.set.debugWrite()()
+	}
+	self.set.ord.MoveToBack(elem)
+	self.set.version++
+	return true
+}
+
+// If the value is missing, inserts it immediately before the cursor's position
+// and returns `true`: before the current value, or at the end of the gap. A
+// backward walk visits it next. If the value is present, or the cursor has
+// lost its position, does nothing and returns `false`.
+func (self *Cursor) InsertBefore(val interface{}) bool {
+	return self.insert(val, true)
+}
+
+// If the value is missing, inserts it immediately after the cursor's position
+// and returns `true`: after the current value, or at the start of the gap. A
+// forward walk visits it next. If the value is present, or the cursor has lost
+// its position, does nothing and returns `false`.
+func (self *Cursor) InsertAfter(val interface{}) bool {
+	return self.insert(val, false)
+}
+
+func (self *Cursor) insert(val interface{}, before bool) bool {
+	if self.set == nil || self.lost || self.set.Has(val) {
+		return false
+	}
+	if debugMode {
+		defer self.set.debugWrite()()
+	}
+
+	ord := &self.set.ord
+	var elem *list.Element
+
+	switch {
+	case self.elem != nil:
+		if !self.live(self.elem) {
+			return self.lose()
+		}
+		if before {
+			elem = ord.InsertBefore(val, self.elem)
+		} else {
+			elem = ord.InsertAfter(val, self.elem)
+		}
+
+	case self.live(self.next):
+		elem = ord.InsertBefore(val, self.next)
+	case self.live(self.prev):
+		elem = ord.InsertAfter(val, self.prev)
+	case self.atStart():
+		elem = ord.PushFront(val)
+	case self.atEnd():
+		elem = ord.PushBack(val)
+	default:
+		return self.lose()
+	}
+
+	self.set.init()
+	self.set.indexElem(elem)
+
+	if self.elem == nil {
+		if before {
+			self.prev = elem
+		} else {
+			self.next = elem
+		}
+		self.end = self.next == nil
+	}
+	return true
+}
+
+// Moves the cursor to the node, or, if it's nil, into the gap beyond `from`.
+func (self *Cursor) moveTo(elem, from *list.Element, forward bool) bool {
+	if elem != nil {
+		self.elem, self.prev, self.next, self.end = elem, nil, nil, false
+		return true
+	}
+
+	self.elem = nil
+	if forward {
+		self.prev, self.next, self.end = from, nil, true
+	} else {
+		self.prev, self.next, self.end = nil, from, false
+	}
+	return false
+}
+
+// If the cursor is on a live value, turns its position into the surrounding
+// gap and returns the node.
+func (self *Cursor) gap() *list.Element {
+	elem := self.elem
+	if !self.live(elem) {
+		return nil
+	}
+	self.elem, self.prev, self.next = nil, elem.Prev(), elem.Next()
+	self.end = self.next == nil
+	return elem
+}
+
+// When both borders are nil, `end` tells the start from the end.
+func (self *Cursor) atStart() bool {
+	return self.prev == nil && (self.next != nil || !self.end)
+}
+
+func (self *Cursor) atEnd() bool {
+	return self.next == nil && (self.prev != nil || self.end)
+}
+
+func (self *Cursor) lose() bool {
+	self.elem, self.prev, self.next, self.lost = nil, nil, nil, true
+	return false
+}
+
+// Removed nodes may linger in cursors. A node is live while the map still
+// points to it.
+func (self *Cursor) live(elem *list.Element) bool {
+	return elem != nil && self.set != nil && self.set.set[elem.Value] == elem
+}
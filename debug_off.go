@@ -0,0 +1,13 @@
+//go:build !gord_debug
+
+package gord
+
+// See the `gord_debug` version.
+const debugMode = false
+
+// Zero-size; must not be the last field of a struct, which would pad it.
+type debugState struct{}
+
+func (*LinkedSet) debugWrite() func() { return nil }
+func (*SliceSet) debugWrite() func()  { return nil }
+func (*statMutex) debugLocked()       {}
@@ -0,0 +1,86 @@
+//go:build gord_debug
+
+package gord
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+/*
+True when built with the `gord_debug` tag. In this mode, mutating methods of
+`LinkedSet` and `SliceSet` panic when they overlap with another mutation of the
+same set, which means it's used from multiple goroutines without
+synchronization. `LinkedSet`, and every concurrency-safe type, also panics
+when used after being copied. Unlike the race detector, this doesn't require
+the race to actually happen in a test run, and reports it at the call site
+rather than deep inside `container/list`.
+
+Without the tag, `debugMode` is false and the checks compile away.
+*/
+const debugMode = true
+
+// Records a mutation in progress, and the address of the value that first used
+// this state. A copy carries the original's address, which exposes it.
+type debugState struct {
+	busy  int32
+	owner unsafe.Pointer
+}
+
+func (self *debugState) enter(owner unsafe.Pointer, typ, alt string) {
+	if !atomic.CompareAndSwapInt32(&self.busy, 0, 1) {
+		panic(fmt.Errorf(`[gord] concurrent mutation of %v from multiple goroutines; use %v or an external lock`, typ, alt))
+	}
+	if !self.owned(owner) {
+		atomic.StoreInt32(&self.busy, 0)
+		panic(fmt.Errorf(`[gord] %v was copied after first use; the copy shares list nodes with the original, corrupting both; pass a pointer instead`, typ))
+	}
+}
+
+func (self *debugState) exit() { atomic.StoreInt32(&self.busy, 0) }
+
+func (self *debugState) owned(owner unsafe.Pointer) bool {
+	prev := atomic.LoadPointer(&self.owner)
+	if prev == nil {
+		return atomic.CompareAndSwapPointer(&self.owner, nil, owner) ||
+			atomic.LoadPointer(&self.owner) == owner
+	}
+	return prev == owner
+}
+
+// Called at the start of every mutating method that doesn't delegate to
+// another one. The returned function must be deferred.
+func (self *LinkedSet) debugWrite() func() {
+	if self == nil {
+		return func() {}
+	}
+	self.debug.enter(unsafe.Pointer(self), `LinkedSet`, `SyncLinkedSet`)
+	return self.debug.exit
+}
+
+// `SliceSet` is a bare slice with nowhere to keep state, so mutations in
+// progress are tracked by address. Copying a slice is harmless.
+var debugSlices sync.Map
+
+// Same as `LinkedSet.debugWrite`.
+func (self *SliceSet) debugWrite() func() {
+	if self == nil {
+		return func() {}
+	}
+	_, busy := debugSlices.LoadOrStore(self, struct{}{})
+	if busy {
+		panic(fmt.Errorf(`[gord] concurrent mutation of SliceSet from multiple goroutines; use SyncSliceSet, CowSliceSet or an external lock`))
+	}
+	return func() { debugSlices.Delete(self) }
+}
+
+// Called after acquiring the lock. A copied set has its own lock, which doesn't
+// exclude users of the original.
+func (self *statMutex) debugLocked() {
+	if !self.debug.owned(unsafe.Pointer(self)) {
+		self.Mutex.Unlock()
+		panic(fmt.Errorf(`[gord] concurrency-safe set was copied after first use; the copy's lock doesn't exclude users of the original; pass a pointer instead`))
+	}
+}
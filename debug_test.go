@@ -0,0 +1,76 @@
+//go:build gord_debug
+
+package gord
+
+import "reflect"
+
+func TestDebugLinkedSet(t *T) {
+	t.Run("concurrent mutation", func(t *T) {
+		set := NewLinkedSet(10, 20)
+
+		// Simulates a mutation in progress on another goroutine.
+		set.debug.busy = 1
+		requirePanic(func() { set.Add(30) })
+		requirePanic(func() { set.PoppedFirst() })
+
+		set.debug.busy = 0
+		set.Add(30)
+		requireEqual([]interface{}{10, 20, 30}, set.Values())
+	})
+
+	t.Run("copy", func(t *T) {
+		set := NewLinkedSet(10, 20)
+		other := *set
+		requirePanic(func() { other.Add(30) })
+		requirePanic(func() { other.Delete(10) })
+
+		set.Add(30)
+		requireEqual([]interface{}{10, 20, 30}, set.Values())
+		requireEqual(nil, set.Validate())
+	})
+
+	t.Run("copy before use", func(t *T) {
+		var set LinkedSet
+		other := set
+		set.Add(10)
+		other.Add(20)
+		requireEqual([]interface{}{10}, set.Values())
+		requireEqual([]interface{}{20}, other.Values())
+	})
+}
+
+func TestDebugSliceSet(t *T) {
+	set := NewSliceSet(10, 20)
+
+	debugSlices.Store(set, struct{}{})
+	requirePanic(func() { set.Add(30) })
+	requirePanic(func() { set.Rotate(1) })
+
+	debugSlices.Delete(set)
+	set.Add(30)
+	requireEqual(SliceSet{10, 20, 30}, *set)
+
+	other := *set
+	other.Delete(10)
+	requireEqual(SliceSet{20, 30}, other)
+}
+
+func TestDebugSyncCopy(t *T) {
+	set := NewSyncLinkedSet(10, 20)
+	set.Add(30)
+
+	// Plain assignment would be rejected by `go vet`.
+	other := new(SyncLinkedSet)
+	reflect.ValueOf(other).Elem().Set(reflect.ValueOf(set).Elem())
+	requirePanic(func() { other.Has(10) })
+
+	set.Add(40)
+	requireEqual([]interface{}{10, 20, 30, 40}, set.Values())
+
+	bag := NewSyncOrdBag(10)
+	bag.Add(20)
+
+	otherBag := new(SyncOrdBag)
+	reflect.ValueOf(otherBag).Elem().Set(reflect.ValueOf(bag).Elem())
+	requirePanic(func() { otherBag.Len() })
+}
@@ -0,0 +1,291 @@
+package gord
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Kind of change described by an `Edit`.
+type EditOp byte
+
+const (
+	// Removes `Edit.Val`, which must be present.
+	EditDelete EditOp = iota + 1
+
+	// Adds `Edit.Val`, which must be missing, at the position described by
+	// `Edit.First` and `Edit.After`.
+	EditInsert
+
+	// Moves `Edit.Val`, which must be present, to the position described by
+	// `Edit.First` and `Edit.After`.
+	EditMove
+)
+
+// Implement `fmt.Stringer`.
+func (self EditOp) String() string {
+	switch self {
+	case EditDelete:
+		return `delete`
+	case EditInsert:
+		return `insert`
+	case EditMove:
+		return `move`
+	default:
+		return fmt.Sprintf(`EditOp(%d)`, byte(self))
+	}
+}
+
+/*
+Single step of an edit script produced by `Diff` and replayed by `Apply`.
+Positions are expressed relative to an anchor, which is a value already in the
+set at the time of the edit, rather than an index, so that edits don't need to
+be reindexed after earlier edits.
+*/
+type Edit struct {
+	Op  EditOp
+	Val interface{}
+
+	// For `EditInsert` and `EditMove`: if true, `Val` goes first, and `After`
+	// is ignored.
+	First bool
+
+	// For `EditInsert` and `EditMove`: if `First` is false, `Val` goes
+	// immediately after this value.
+	After interface{}
+}
+
+// Implement `fmt.Stringer`, for debugging.
+func (self Edit) String() string {
+	switch {
+	case self.Op == EditDelete:
+		return fmt.Sprintf(`%v %#v`, self.Op, self.Val)
+	case self.First:
+		return fmt.Sprintf(`%v %#v first`, self.Op, self.Val)
+	default:
+		return fmt.Sprintf(`%v %#v after %#v`, self.Op, self.Val, self.After)
+	}
+}
+
+/*
+Computes a minimal edit script that turns `prev` into `next`, similar to keyed
+list reconciliation in UI libraries. The script consists of deletions of values
+missing from `next`, followed by insertions and moves in the order of `next`.
+Values common to both sets that form the longest increasing subsequence of
+positions in `next` stay in place; every other common value is moved once. This
+minimizes the number of moves.
+
+Takes O(n log n) time, where n is the combined size of the sets. Replay the
+script with `Apply`.
+*/
+func Diff(prev, next OrdSet) []Edit {
+	src, tar := prev.Values(), next.Values()
+
+	positions := make(map[interface{}]int, len(tar))
+	for i, val := range tar {
+		positions[val] = i
+	}
+
+	var out []Edit
+	kept := make([]int, 0, len(src))
+	for _, val := range src {
+		pos, ok := positions[val]
+		if ok {
+			kept = append(kept, pos)
+		} else {
+			out = append(out, Edit{Op: EditDelete, Val: val})
+		}
+	}
+
+	present := make([]bool, len(tar))
+	for _, pos := range kept {
+		present[pos] = true
+	}
+
+	stable := make([]bool, len(tar))
+	for _, pos := range longestIncreasing(kept) {
+		stable[pos] = true
+	}
+
+	for i, val := range tar {
+		if stable[i] {
+			continue
+		}
+
+		edit := Edit{Op: EditInsert, Val: val, First: i == 0}
+		if present[i] {
+			edit.Op = EditMove
+		}
+		if i > 0 {
+			edit.After = tar[i-1]
+		}
+		out = append(out, edit)
+	}
+
+	return out
+}
+
+/*
+Replays an edit script produced by `Diff`. Returns an error at the first edit
+that doesn't match the set's contents, such as deleting a missing value or
+referencing a missing anchor; in this case, the set may be partially updated.
+
+Has special support for every type in this package, updating `LinkedSet` in
+O(1) per edit and concurrency-safe types atomically. Other implementations of
+`OrdSet` are updated via `.Delete` and `.AddLast`.
+*/
+func Apply(set OrdSet, edits []Edit) error {
+	switch set := set.(type) {
+	case *LinkedSet:
+		return set.apply(edits)
+
+	case *SyncLinkedSet:
+		var err error
+		set.Update(func(set *LinkedSet) { err = set.apply(edits) })
+		return err
+
+	case *SliceSet:
+		return applySlice(set, edits)
+
+	case *SyncSliceSet:
+		var err error
+		set.Update(func(set *SliceSet) { err = applySlice(set, edits) })
+		return err
+
+	case *CowSliceSet:
+		var err error
+		set.Update(func(set *SliceSet) { err = applySlice(set, edits) })
+		return err
+
+	default:
+		return applyOrdSet(set, edits)
+	}
+}
+
+func (self *LinkedSet) apply(edits []Edit) error {
+	for i, edit := range edits {
+		err := self.applyEdit(edit)
+		if err != nil {
+			return fmt.Errorf(`[gord] unable to apply edit %v (%v): %w`, i, edit, err)
+		}
+	}
+	return nil
+}
+
+func (self *LinkedSet) applyEdit(edit Edit) error {
+	elem := self.set[edit.Val]
+
+	switch edit.Op {
+	case EditDelete:
+		if elem == nil {
+			return fmt.Errorf(`value is missing`)
+		}
+		self.removeElem(elem)
+		return nil
+
+	case EditInsert:
+		if elem != nil {
+			return fmt.Errorf(`value is already present`)
+		}
+
+	case EditMove:
+		if elem == nil {
+			return fmt.Errorf(`value is missing`)
+		}
+
+	default:
+		return fmt.Errorf(`unknown operation`)
+	}
+
+	if edit.First {
+		if elem == nil {
+			self.AddFirst(edit.Val)
+		} else {
+			self.ord.MoveToFront(elem)
+			self.version++
+		}
+		return nil
+	}
+
+	mark := self.set[edit.After]
+	if mark == nil {
+		return fmt.Errorf(`anchor %#v is missing`, edit.After)
+	}
+	if mark == elem {
+		return fmt.Errorf(`value can't be its own anchor`)
+	}
+
+	if elem == nil {
+		self.indexElem(self.ord.InsertAfter(edit.Val, mark))
+	} else {
+		self.ord.MoveAfter(elem, mark)
+		self.version++
+	}
+	return nil
+}
+
+func applySlice(set *SliceSet, edits []Edit) error {
+	temp := NewLinkedSet(*set...)
+	err := temp.apply(edits)
+	if err != nil {
+		return err
+	}
+	*set = set.truncate(0)
+	*set = append(*set, temp.Values()...)
+	return nil
+}
+
+func applyOrdSet(set OrdSet, edits []Edit) error {
+	temp := NewLinkedSet(set.Values()...)
+	err := temp.apply(edits)
+	if err != nil {
+		return err
+	}
+
+	// `.Values` may return the set's own storage, which deletion would shift.
+	for _, val := range append([]interface{}(nil), set.Values()...) {
+		if !temp.Has(val) {
+			set.Delete(val)
+		}
+	}
+	temp.Each(func(_ int, val interface{}) {
+		set.AddLast(val)
+	})
+	return nil
+}
+
+/*
+Returns the elements of the longest strictly increasing subsequence, using
+patience sorting. Takes O(n log n) time.
+*/
+func longestIncreasing(vals []int) []int {
+	// Indexes of the smallest tails of increasing subsequences of each length.
+	var tails []int
+	prevs := make([]int, len(vals))
+
+	for i, val := range vals {
+		size := sort.Search(len(tails), func(ind int) bool {
+			return vals[tails[ind]] >= val
+		})
+
+		if size > 0 {
+			prevs[i] = tails[size-1]
+		} else {
+			prevs[i] = -1
+		}
+
+		if size == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[size] = i
+		}
+	}
+
+	out := make([]int, len(tails))
+	if len(tails) == 0 {
+		return out
+	}
+	for i, ind := len(out)-1, tails[len(tails)-1]; i >= 0; i, ind = i-1, prevs[ind] {
+		out[i] = vals[ind]
+	}
+	return out
+}
@@ -0,0 +1,281 @@
+package gord
+
+import (
+	"math"
+	"math/bits"
+	"reflect"
+)
+
+/*
+Returns an order-insensitive 64-bit hash of the set's values: sets with the same
+values have the same fingerprint regardless of order or type. Combines value
+hashes commutatively, which allows `LinkedSet` to maintain it incrementally:
+the first call takes O(n), and subsequent calls take O(1), at the cost of
+hashing every added or deleted value from then on.
+
+Hashes of strings, booleans and numbers are stable across processes and
+versions of this package. Numbers that are equal as map keys hash equally, so
+`0.0` and `-0.0` hash the same; every NaN hashes the same, although NaNs are
+never equal to each other. Pointers and channels are hashed by address, like
+map keys. Other values, such as structs and arrays, are hashed field by field
+via reflection, following the same rules, so values that are equal as map keys
+hash equally at any depth. Hashes that involve addresses are stable only within
+the same process. As with any hash,
+different sets may collide; use fingerprints to detect changes, not to prove
+equality.
+*/
+func (self *LinkedSet) Fingerprint() uint64 {
+	if self == nil {
+		return 0
+	}
+	if debugMode {
+		defer self.debugWrite()()
+	}
+	if !self.hashed {
+		self.hash = 0
+		for val := range self.set {
+			self.hash += hashVal(val)
+		}
+		self.hashed = true
+	}
+	return self.hash
+}
+
+/*
+Returns an order-sensitive 64-bit hash of the set's values: sets with the same
+values in the same order have the same fingerprint regardless of type. Always
+takes O(n). See `.Fingerprint` for the stability guarantees.
+*/
+func (self *LinkedSet) OrdFingerprint() uint64 {
+	var hash ordHash
+	if self != nil {
+		self.eachWhile(hash.add)
+	}
+	return hash.sum()
+}
+
+// Concurrency-safe version of `LinkedSet.Fingerprint`.
+func (self *SyncLinkedSet) Fingerprint() uint64 {
+	if self == nil {
+		return 0
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.set.Fingerprint()
+}
+
+// Concurrency-safe version of `LinkedSet.OrdFingerprint`.
+func (self *SyncLinkedSet) OrdFingerprint() uint64 {
+	if self == nil {
+		return ordHash(0).sum()
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.set.OrdFingerprint()
+}
+
+// Same as `LinkedSet.Fingerprint`, but always takes O(n), since there's nowhere
+// to store the running hash.
+func (self *SliceSet) Fingerprint() uint64 {
+	if self == nil {
+		return 0
+	}
+	return unordHash(*self)
+}
+
+// Same as `LinkedSet.OrdFingerprint`.
+func (self *SliceSet) OrdFingerprint() uint64 {
+	var hash ordHash
+	if self != nil {
+		self.eachWhile(hash.add)
+	}
+	return hash.sum()
+}
+
+// Concurrency-safe version of `SliceSet.Fingerprint`.
+func (self *SyncSliceSet) Fingerprint() uint64 {
+	if self == nil {
+		return 0
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.set.Fingerprint()
+}
+
+// Concurrency-safe version of `SliceSet.OrdFingerprint`.
+func (self *SyncSliceSet) OrdFingerprint() uint64 {
+	if self == nil {
+		return ordHash(0).sum()
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.set.OrdFingerprint()
+}
+
+// Concurrency-safe version of `SliceSet.Fingerprint`. Doesn't lock.
+func (self *CowSliceSet) Fingerprint() uint64 {
+	set := self.load()
+	return set.Fingerprint()
+}
+
+// Concurrency-safe version of `SliceSet.OrdFingerprint`. Doesn't lock.
+func (self *CowSliceSet) OrdFingerprint() uint64 {
+	set := self.load()
+	return set.OrdFingerprint()
+}
+
+func unordHash(vals []interface{}) (out uint64) {
+	for _, val := range vals {
+		out += hashVal(val)
+	}
+	return
+}
+
+// Running order-sensitive hash. Every step depends on the previous state, so
+// swapping any two values changes the result.
+type ordHash uint64
+
+func (self *ordHash) add(val interface{}) bool {
+	self.mix(hashVal(val))
+	return true
+}
+
+func (self *ordHash) mix(hash uint64) {
+	*self = ordHash(mixHash(bits.RotateLeft64(uint64(*self), 23) ^ hash))
+}
+
+func (self ordHash) sum() uint64 { return mixHash(uint64(self) ^ 0x9e3779b97f4a7c15) }
+
+// Values of different types hash differently, since they're different map
+// keys. Numbers are hashed by their bits, strings via FNV-1a; each with a
+// distinct type tag. Values that are equal as map keys must hash equally; see
+// `floatBits`.
+func hashVal(val interface{}) uint64 {
+	switch val := val.(type) {
+	case nil:
+		return hashTagged(1, 0)
+	case bool:
+		if val {
+			return hashTagged(2, 1)
+		}
+		return hashTagged(2, 0)
+	case string:
+		return hashTagged(3, fnvHash(val))
+	case int:
+		return hashTagged(4, uint64(val))
+	case int8:
+		return hashTagged(5, uint64(val))
+	case int16:
+		return hashTagged(6, uint64(val))
+	case int32:
+		return hashTagged(7, uint64(val))
+	case int64:
+		return hashTagged(8, uint64(val))
+	case uint:
+		return hashTagged(9, uint64(val))
+	case uint8:
+		return hashTagged(10, uint64(val))
+	case uint16:
+		return hashTagged(11, uint64(val))
+	case uint32:
+		return hashTagged(12, uint64(val))
+	case uint64:
+		return hashTagged(13, val)
+	case uintptr:
+		return hashTagged(14, uint64(val))
+	case float32:
+		return hashTagged(15, floatBits(float64(val)))
+	case float64:
+		return hashTagged(16, floatBits(val))
+	case complex64:
+		return hashTagged(18, floatBits(float64(real(val)))^bits.RotateLeft64(floatBits(float64(imag(val))), 32))
+	case complex128:
+		return hashTagged(19, floatBits(real(val))^bits.RotateLeft64(floatBits(imag(val)), 32))
+	}
+	return hashReflect(reflect.ValueOf(val))
+}
+
+// Same rules as `hashVal`, for other types, including named types and fields of
+// structs. Tagged by the type name. Fields are combined like `ordHash`, so
+// reordering them changes the result. Pointers and channels are hashed by
+// address rather than by their contents, which may change after the value is
+// added, and may be equal for distinct pointers.
+func hashReflect(ref reflect.Value) uint64 {
+	tag := fnvHash(ref.Type().String())
+
+	switch ref.Kind() {
+	case reflect.Bool:
+		if ref.Bool() {
+			return hashTagged(tag, 1)
+		}
+		return hashTagged(tag, 0)
+	case reflect.String:
+		return hashTagged(tag, fnvHash(ref.String()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return hashTagged(tag, uint64(ref.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return hashTagged(tag, ref.Uint())
+	case reflect.Float32, reflect.Float64:
+		return hashTagged(tag, floatBits(ref.Float()))
+	case reflect.Complex64, reflect.Complex128:
+		val := ref.Complex()
+		return hashTagged(tag, floatBits(real(val))^bits.RotateLeft64(floatBits(imag(val)), 32))
+	case reflect.Interface:
+		if ref.IsNil() {
+			return hashTagged(tag, 0)
+		}
+		return hashTagged(tag, hashReflect(ref.Elem()))
+	case reflect.Array:
+		var out ordHash
+		for i := 0; i < ref.Len(); i++ {
+			out.mix(hashReflect(ref.Index(i)))
+		}
+		return hashTagged(tag, out.sum())
+	case reflect.Struct:
+		var out ordHash
+		for i := 0; i < ref.NumField(); i++ {
+			out.mix(hashReflect(ref.Field(i)))
+		}
+		return hashTagged(tag, out.sum())
+	default:
+		return hashTagged(tag, uint64(ref.Pointer()))
+	}
+}
+
+// Converting to `float64` is exact, so `float32` values may share this. Equal
+// floats must hash equally, but `-0` has a different sign bit than `+0`. NaNs
+// are never equal, so any hash is valid, but normalizing them keeps the
+// fingerprint independent of which NaN payload is stored.
+func floatBits(val float64) uint64 {
+	if val == 0 {
+		return 0
+	}
+	if val != val {
+		return math.Float64bits(math.NaN())
+	}
+	return math.Float64bits(val)
+}
+
+func hashTagged(tag, val uint64) uint64 {
+	return mixHash(val ^ mixHash(tag))
+}
+
+// 64-bit FNV-1a. Unlike `hash/fnv`, doesn't allocate.
+func fnvHash(str string) uint64 {
+	out := uint64(14695981039346656037)
+	for i := 0; i < len(str); i++ {
+		out ^= uint64(str[i])
+		out *= 1099511628211
+	}
+	return out
+}
+
+// Finalizer from SplitMix64; spreads every input bit across the output.
+func mixHash(val uint64) uint64 {
+	val ^= val >> 30
+	val *= 0xbf58476d1ce4e5b9
+	val ^= val >> 27
+	val *= 0x94d049bb133111eb
+	val ^= val >> 31
+	return val
+}
@@ -0,0 +1,158 @@
+package gord
+
+import (
+	"fmt"
+	"strconv"
+)
+
+/*
+Implement `fmt.Formatter`. Writes the values directly to the output, without
+allocating a slice of every value. Supports the following:
+
+	%v    [20 10 30]
+	%+v   *gord.LinkedSet(len=3)[20 10 30]
+	%#v   NewLinkedSet(20, 10, 30)
+	%.2v  [20 10 …(+1 more)]
+	%d    [20 10 30]
+	%4d   [  20   10   30]
+
+Precision limits the number of printed values, which makes it safe to log huge
+sets. Other flags, width, and the verb itself are applied to each value, as in
+formatting of slices.
+*/
+func (self *LinkedSet) Format(out fmt.State, verb rune) {
+	if self == nil {
+		formatNil(out, verb, `(*LinkedSet)(nil)`)
+		return
+	}
+	linkedSetFormat.format(out, verb, self.Len(), self.eachWhile)
+}
+
+// Concurrency-safe version of `LinkedSet.Format`. Holds the lock while
+// writing.
+func (self *SyncLinkedSet) Format(out fmt.State, verb rune) {
+	if self == nil {
+		formatNil(out, verb, `(*SyncLinkedSet)(nil)`)
+		return
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	syncLinkedSetFormat.format(out, verb, self.set.Len(), self.set.eachWhile)
+}
+
+// Same as `LinkedSet.Format`. With `%#v`, prints a composite literal such as
+// `SliceSet{20, 10, 30}`.
+func (self *SliceSet) Format(out fmt.State, verb rune) {
+	if self == nil {
+		formatNil(out, verb, `(*SliceSet)(nil)`)
+		return
+	}
+	if *self == nil && verb == 'v' && out.Flag('#') {
+		writeString(out, `SliceSet(nil)`)
+		return
+	}
+	sliceSetFormat.format(out, verb, len(*self), self.eachWhile)
+}
+
+// Concurrency-safe version of `SliceSet.Format`. Holds the lock while writing.
+func (self *SyncSliceSet) Format(out fmt.State, verb rune) {
+	if self == nil {
+		formatNil(out, verb, `(*SyncSliceSet)(nil)`)
+		return
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	syncSliceSetFormat.format(out, verb, len(self.set), self.set.eachWhile)
+}
+
+// Concurrency-safe version of `SliceSet.Format`. Doesn't lock.
+func (self *CowSliceSet) Format(out fmt.State, verb rune) {
+	if self == nil {
+		formatNil(out, verb, `(*CowSliceSet)(nil)`)
+		return
+	}
+	set := self.load()
+	cowSliceSetFormat.format(out, verb, len(set), set.eachWhile)
+}
+
+var (
+	linkedSetFormat     = setFormat{`*gord.LinkedSet`, `NewLinkedSet(`, `)`}
+	syncLinkedSetFormat = setFormat{`*gord.SyncLinkedSet`, `NewSyncLinkedSet(`, `)`}
+	sliceSetFormat      = setFormat{`*gord.SliceSet`, `SliceSet{`, `}`}
+	syncSliceSetFormat  = setFormat{`*gord.SyncSliceSet`, `NewSyncSliceSet(`, `)`}
+	cowSliceSetFormat   = setFormat{`*gord.CowSliceSet`, `NewCowSliceSet(`, `)`}
+)
+
+type setFormat struct {
+	typ     string
+	goOpen  string
+	goClose string
+}
+
+func (self setFormat) format(
+	out fmt.State, verb rune, size int, each func(func(interface{}) bool),
+) {
+	goSyntax := verb == 'v' && out.Flag('#')
+	limit, limited := out.Precision()
+	if !limited || limit > size {
+		limit = size
+	}
+
+	open, sep, close := `[`, ` `, `]`
+	if goSyntax {
+		open, sep, close = self.goOpen, `, `, self.goClose
+	} else if verb == 'v' && out.Flag('+') {
+		writeString(out, self.typ+`(len=`+strconv.Itoa(size)+`)`)
+	}
+
+	elemFormat := formatElem(out, verb)
+	writeString(out, open)
+
+	count := 0
+	each(func(val interface{}) bool {
+		if count >= limit {
+			return false
+		}
+		if count > 0 {
+			writeString(out, sep)
+		}
+		fmt.Fprintf(out, elemFormat, val)
+		count++
+		return true
+	})
+
+	if count < size {
+		if count > 0 {
+			writeString(out, sep)
+		}
+		writeString(out, `…(+`+strconv.Itoa(size-count)+` more)`)
+	}
+	writeString(out, close)
+}
+
+// Reconstructs the format directive for individual values, excluding the
+// precision, which is used as the limit.
+func formatElem(out fmt.State, verb rune) string {
+	buf := []byte{'%'}
+	for _, flag := range `+-# 0` {
+		if out.Flag(int(flag)) {
+			buf = append(buf, byte(flag))
+		}
+	}
+	if width, ok := out.Width(); ok {
+		buf = strconv.AppendInt(buf, int64(width), 10)
+	}
+	return string(append(buf, string(verb)...))
+}
+
+func formatNil(out fmt.State, verb rune, goString string) {
+	if verb == 'v' && out.Flag('#') {
+		writeString(out, goString)
+		return
+	}
+	writeString(out, `[]`)
+}
+
+func writeString(out fmt.State, str string) {
+	_, _ = out.Write([]byte(str))
+}
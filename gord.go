@@ -10,6 +10,11 @@ list.
 • `SliceSet`: slice-backed ordered set. Simpler and faster for small sets,
 extreme performance degradation for large sets.
 
+• `SyncSliceSet`: concurrency-safe `SliceSet` using a mutex.
+
+• `CowSliceSet`: concurrency-safe, copy-on-write `SliceSet` with lock-free
+reads. Best for small, read-heavy sets.
+
 Installation
 
 Simply import:
@@ -24,11 +29,15 @@ See the example below for `OrdSet` / `NewOrdSet`.
 */
 package gord
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+)
 
 // Interface that describes an arbitrary set, but not necessarily an ordered
-// set. Satisfied by every type in this package. See `OrdSet` for the full
-// interface.
+// set. Satisfied by every type in this package, except `TypedLinkedSet` and
+// its specializations, whose methods take typed values; see
+// `TypedLinkedSet.OrdSet`. See `OrdSet` for the full interface.
 type Set interface {
 	// Current set size, replacement for `len(set)`.
 	Len() int
@@ -41,7 +50,7 @@ type Set interface {
 
 	// If `set.Has(val)`, has no effect and returns `false`.
 	// If `!set.Has(val)`, appends `val` to the end and returns `true`.
-	// In ordered sets (every type in this package), must not change the order.
+	// In ordered sets, must not change the order.
 	Added(val interface{}) bool
 
 	// Void version of `.Deleted`.
@@ -53,7 +62,9 @@ type Set interface {
 }
 
 // Interface that describes an ordered set. Strict superset of `Set`. Satisfied
-// by every type in this package.
+// by `LinkedSet`, `SliceSet`, their concurrency-safe versions, `WalSet` and
+// `CountingSet`. Sets with their own ordering, such as `ScoredSet`, only
+// satisfy `Set`.
 type OrdSet interface {
 	Set
 
@@ -94,16 +105,42 @@ type OrdSet interface {
 	Values() []interface{}
 }
 
-// Describes a set with extra printing methods. Satisfied by every type in this
-// package.
+/*
+Describes an ordered set that can be navigated without mutating it. Satisfied by
+`LinkedSet`, `SliceSet`, their concurrency-safe versions, and `WalSet`. Kept
+separate from `OrdSet` so that other implementations of `OrdSet` don't have to
+implement these methods.
+*/
+type NavOrdSet interface {
+	OrdSet
+
+	// If the set is empty, returns `(nil, false)`.
+	// Otherwise returns `(val, true)` for the first value, without removing it.
+	First() (interface{}, bool)
+
+	// If the set is empty, returns `(nil, false)`.
+	// Otherwise returns `(val, true)` for the last value, without removing it.
+	Last() (interface{}, bool)
+
+	// If `set.Has(val)` and the value is not last, returns `(next, true)` for
+	// the value that follows it. Otherwise returns `(nil, false)`.
+	Next(val interface{}) (interface{}, bool)
+
+	// If `set.Has(val)` and the value is not first, returns `(prev, true)` for
+	// the value that precedes it. Otherwise returns `(nil, false)`.
+	Prev(val interface{}) (interface{}, bool)
+}
+
+// Describes a set with extra printing methods. Satisfied by `LinkedSet`,
+// `SliceSet` and their concurrency-safe versions.
 type StringerSet interface {
 	Set
 	fmt.Stringer
 	fmt.GoStringer
 }
 
-// Describes an ordered set with extra printing methods. Satisfied by every type
-// in this package.
+// Describes an ordered set with extra printing methods. Satisfied by
+// `LinkedSet`, `SliceSet` and their concurrency-safe versions.
 type StringerOrdSet interface {
 	OrdSet
 	fmt.Stringer
@@ -114,3 +151,71 @@ type StringerOrdSet interface {
 func NewOrdSet(vals ...interface{}) OrdSet {
 	return NewLinkedSet(vals...)
 }
+
+// Clamps a slice-style range to `[0, size]`, ensuring `start <= end`.
+func clampRange(start, end, size int) (int, int) {
+	if start < 0 {
+		start = 0
+	}
+	if start > size {
+		start = size
+	}
+	if end > size {
+		end = size
+	}
+	if end < start {
+		end = start
+	}
+	return start, end
+}
+
+// Clamps a count to `[0, size]`.
+func clampCount(count, size int) int {
+	if count < 0 {
+		return 0
+	}
+	if count > size {
+		return size
+	}
+	return count
+}
+
+/*
+Panic value of iterators such as `LinkedSet.Each` when the callback modifies
+the set being iterated, which would otherwise silently skip or repeat values.
+Detected as soon as the callback returns, by comparing `LinkedSet.Version`.
+For `SliceSet`, see `SliceSet.Each`.
+*/
+type ConcurrentModificationError struct {
+	// Index of the value whose callback modified the set.
+	Index int
+	Value interface{}
+}
+
+// Implement `error`.
+func (self *ConcurrentModificationError) Error() string {
+	return fmt.Sprintf(`[gord] set modified during iteration by the callback for value %#v at index %v; iterate over a copy of the values instead`, self.Value, self.Index)
+}
+
+// Adapts an iteration function with an early exit to a callback that takes an
+// index and may fail.
+func eachErr(each func(func(interface{}) bool), fun func(int, interface{}) error) (err error) {
+	i := 0
+	each(func(val interface{}) bool {
+		err = fun(i, val)
+		i++
+		return err == nil
+	})
+	return
+}
+
+// Wraps the callback to check the context first.
+func eachCtx(ctx context.Context, fun func(int, interface{}) error) func(int, interface{}) error {
+	return func(i int, val interface{}) error {
+		err := ctx.Err()
+		if err != nil {
+			return err
+		}
+		return fun(i, val)
+	}
+}
@@ -1,11 +1,29 @@
 package gord
 
 import (
+	"bufio"
+	"bytes"
 	"container/list"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"math"
 	"math/rand"
+	"os"
+	"path/filepath"
 	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 type T = testing.T
@@ -72,12 +90,17 @@ func TestSliceSetValues(t *T) {
 }
 
 func TestLinkedSet(t *T) {
-	testSet(t, func() OrdSet { return new(LinkedSet) })
-
 	t.Run("New", func(t *T) {
 		testSetNew(func(args ...interface{}) OrdSet { return NewLinkedSet(args...) })
 	})
 
+	t.Run("Range", func(t *T) {
+		testSetRange(func(args ...interface{}) OrdSet { return NewLinkedSet(args...) })
+	})
+
+	testBatchSet(t, func() batchSet { return new(LinkedSet) })
+	testReorderSet(t, func() reorderSet { return new(LinkedSet) })
+
 	t.Run("String", func(t *T) {
 		requireEqual(`[]`, (*LinkedSet)(nil).String())
 		testSetString(new(LinkedSet))
@@ -87,15 +110,22 @@ func TestLinkedSet(t *T) {
 		requireEqual(`(*LinkedSet)(nil)`, (*LinkedSet)(nil).GoString())
 		testLinkedSetGoString(new(LinkedSet), `NewLinkedSet`)
 	})
+
+	testCompoundSet(t, func() compoundSet { return new(LinkedSet) })
 }
 
 func TestSyncLinkedSet(t *T) {
-	testSet(t, func() OrdSet { return new(SyncLinkedSet) })
-
 	t.Run("New", func(t *T) {
 		testSetNew(func(args ...interface{}) OrdSet { return NewSyncLinkedSet(args...) })
 	})
 
+	t.Run("Range", func(t *T) {
+		testSetRange(func(args ...interface{}) OrdSet { return NewSyncLinkedSet(args...) })
+	})
+
+	testBatchSet(t, func() batchSet { return new(SyncLinkedSet) })
+	testReorderSet(t, func() reorderSet { return new(SyncLinkedSet) })
+
 	t.Run("String", func(t *T) {
 		requireEqual(`[]`, (*SyncLinkedSet)(nil).String())
 		testSetString(new(SyncLinkedSet))
@@ -105,441 +135,3115 @@ func TestSyncLinkedSet(t *T) {
 		requireEqual(`(*SyncLinkedSet)(nil)`, (*SyncLinkedSet)(nil).GoString())
 		testLinkedSetGoString(new(SyncLinkedSet), `NewSyncLinkedSet`)
 	})
+
+	testCompoundSet(t, func() compoundSet { return new(SyncLinkedSet) })
+
+	t.Run("Update", func(t *T) {
+		var set SyncLinkedSet
+		var wg sync.WaitGroup
+
+		// Each goroutine atomically replaces 10 with its own value only if 10 is
+		// still present, so exactly one of them succeeds.
+		set.Add(10)
+		var wins int32
+		for i := range counter(16) {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				set.Update(func(set *LinkedSet) {
+					if set.Has(10) && !set.Has(20) {
+						set.Delete(10)
+						set.AddFirst(20)
+						atomic.AddInt32(&wins, 1)
+					}
+				})
+			}(i)
+		}
+		wg.Wait()
+
+		requireEqual(int32(1), wins)
+		requireEqual([]interface{}{20}, set.Values())
+	})
+
+	t.Run("View", func(t *T) {
+		set := NewSyncLinkedSet(20, 10, 30)
+		var vals []interface{}
+		set.View(func(set *LinkedSet) { vals = set.Values() })
+		requireEqual([]interface{}{20, 10, 30}, vals)
+
+		(*SyncLinkedSet)(nil).View(func(set *LinkedSet) { requireEqual(0, set.Len()) })
+	})
 }
 
 func TestSliceSet(t *T) {
-	testSet(t, func() OrdSet { return new(SliceSet) })
-
 	t.Run("New", func(t *T) {
 		testSetNew(func(args ...interface{}) OrdSet { return NewSliceSet(args...) })
 	})
 
-	t.Run("String", func(t *T) {
-		requireEqual(`[]`, (*SliceSet)(nil).String())
-		var set SliceSet
-		requireEqual(`[]`, set.String())
-		testSetString(new(SliceSet))
-	})
+	t.Run("Range", func(t *T) {
+		testSetRange(func(args ...interface{}) OrdSet { return NewSliceSet(args...) })
+	})
+
+	testBatchSet(t, func() batchSet { return new(SliceSet) })
+	testReorderSet(t, func() reorderSet { return new(SliceSet) })
+
+	t.Run("String", func(t *T) {
+		requireEqual(`[]`, (*SliceSet)(nil).String())
+		var set SliceSet
+		requireEqual(`[]`, set.String())
+		testSetString(new(SliceSet))
+	})
+
+	t.Run("GoString", func(t *T) {
+		requireEqual(`(*SliceSet)(nil)`, (*SliceSet)(nil).GoString())
+
+		var set SliceSet
+		requireEqual(`SliceSet(nil)`, set.GoString())
+
+		set.Add(20)
+		requireEqual(`SliceSet{20}`, set.GoString())
+
+		set.Add(10)
+		set.Add(30)
+		requireEqual(`SliceSet{20, 10, 30}`, set.GoString())
+	})
+}
+
+func TestSyncSliceSet(t *T) {
+	t.Run("New", func(t *T) {
+		testSetNew(func(args ...interface{}) OrdSet { return NewSyncSliceSet(args...) })
+	})
+
+	t.Run("Range", func(t *T) {
+		testSetRange(func(args ...interface{}) OrdSet { return NewSyncSliceSet(args...) })
+	})
+
+	testBatchSet(t, func() batchSet { return new(SyncSliceSet) })
+	testReorderSet(t, func() reorderSet { return new(SyncSliceSet) })
+
+	t.Run("String", func(t *T) {
+		requireEqual(`[]`, (*SyncSliceSet)(nil).String())
+		testSetString(new(SyncSliceSet))
+	})
+
+	t.Run("GoString", func(t *T) {
+		requireEqual(`(*SyncSliceSet)(nil)`, (*SyncSliceSet)(nil).GoString())
+		testLinkedSetGoString(new(SyncSliceSet), `NewSyncSliceSet`)
+	})
+
+	t.Run("Values", func(t *T) {
+		set := NewSyncSliceSet(20, 10, 30)
+		vals := set.Values()
+		vals[0] = 40
+		requireEqual([]interface{}{20, 10, 30}, set.Values())
+	})
+
+	t.Run("Update", func(t *T) {
+		set := NewSyncSliceSet(20, 10)
+		set.Update(func(set *SliceSet) {
+			if set.Has(10) {
+				set.Delete(10)
+				set.AddFirst(30)
+			}
+		})
+		requireEqual([]interface{}{30, 20}, set.Values())
+	})
+
+	t.Run("concurrent", func(t *T) { testConcurrentSet(new(SyncSliceSet)) })
+}
+
+func TestCowSliceSet(t *T) {
+	t.Run("New", func(t *T) {
+		testSetNew(func(args ...interface{}) OrdSet { return NewCowSliceSet(args...) })
+	})
+
+	t.Run("Range", func(t *T) {
+		testSetRange(func(args ...interface{}) OrdSet { return NewCowSliceSet(args...) })
+	})
+
+	testBatchSet(t, func() batchSet { return new(CowSliceSet) })
+	testReorderSet(t, func() reorderSet { return new(CowSliceSet) })
+
+	t.Run("String", func(t *T) {
+		requireEqual(`[]`, (*CowSliceSet)(nil).String())
+		testSetString(new(CowSliceSet))
+	})
+
+	t.Run("GoString", func(t *T) {
+		requireEqual(`(*CowSliceSet)(nil)`, (*CowSliceSet)(nil).GoString())
+		testLinkedSetGoString(new(CowSliceSet), `NewCowSliceSet`)
+	})
+
+	// Published snapshots must stay intact after later writes.
+	t.Run("Values", func(t *T) {
+		set := NewCowSliceSet(20, 10, 30)
+		vals := set.Values()
+
+		set.AddFirst(30)
+		set.Delete(10)
+		set.Add(40)
+		_, _ = set.PoppedLast()
+
+		requireEqual([]interface{}{20, 10, 30}, vals)
+		requireEqual([]interface{}{30, 20}, set.Values())
+	})
+
+	// Readers may still hold the drained snapshot.
+	t.Run("Drain", func(t *T) {
+		set := NewCowSliceSet(20, 10, 30)
+		var snapshot *SliceSet
+		set.View(func(set *SliceSet) { snapshot = set })
+
+		vals := set.Drain()
+		vals[0] = 40
+		requireEqual(SliceSet{20, 10, 30}, *snapshot)
+		requireEqual(0, set.Len())
+	})
+
+	// Operations that change nothing must not publish a copy.
+	t.Run("no-op", func(t *T) {
+		set := NewCowSliceSet(20, 10, 30)
+		vals := set.Values()
+
+		requireEqual(false, set.Added(10))
+		requireEqual(false, set.Deleted(40))
+		requireEqual(false, set.AddedFirst(20))
+		requireEqual(false, set.AddedLast(30))
+		requireEqual(true, set.Swapped(10, 10))
+		requireEqual(false, set.Swapped(10, 40))
+		requireEqual(true, set.MovedTo(10, 1))
+		requireEqual(false, set.MovedTo(10, 3))
+		set.Rotate(3)
+		requireEqual([]interface{}(nil), set.PoppedFirstN(0))
+		requireEqual([]interface{}(nil), set.PoppedLastN(-1))
+		set.Update(func(set *SliceSet) {
+			set.Delete(30)
+			set.Add(30)
+		})
+		requireEqual(&vals[0], &set.Values()[0])
+
+		empty := new(CowSliceSet)
+		requireEqual(pair{nil, false}, toPair(empty.PoppedFirst()))
+		requireEqual(pair{nil, false}, toPair(empty.PoppedLast()))
+		requireEqual(nil, empty.val.Load())
+	})
+
+	t.Run("Update", func(t *T) {
+		set := NewCowSliceSet(20, 10)
+		before := set.Values()
+		set.Update(func(set *SliceSet) {
+			set.Delete(10)
+			set.AddFirst(30)
+		})
+		requireEqual([]interface{}{20, 10}, before)
+		requireEqual([]interface{}{30, 20}, set.Values())
+
+		version := set.Version()
+		set.Update(func(set *SliceSet) { set.Swap(30, 20); set.Swap(20, 30) })
+		requireEqual(version, set.Version())
+	})
+
+	t.Run("concurrent", func(t *T) { testConcurrentSet(new(CowSliceSet)) })
+}
+
+// Mostly useful with `go test -race`.
+func testConcurrentSet(set OrdSet) {
+	var wg sync.WaitGroup
+	for i := range counter(8) {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := range counter(64) {
+				set.Add(i*64 + j)
+				_ = set.Has(j)
+				_ = set.Values()
+				if j%2 == 0 {
+					set.Delete(i*64 + j)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	requireEqual(8*32, set.Len())
+}
+
+func TestTTLSet(t *T) {
+	newSet := func() (*TTLSet, *fakeClock, *[]interface{}) {
+		clock := &fakeClock{now: time.Unix(1000, 0)}
+		var expired []interface{}
+		set := &TTLSet{
+			TTL:      10 * time.Second,
+			Clock:    clock,
+			OnExpire: func(val interface{}) { expired = append(expired, val) },
+		}
+		return set, clock, &expired
+	}
+
+	t.Run("nil", func(t *T) {
+		var set *TTLSet
+		requireEqual(0, set.Len())
+		requireEqual(false, set.Has(10))
+		requireEqual(false, set.Deleted(10))
+		requireEqual(0, set.Expire())
+		requireEqual([]interface{}(nil), set.Values())
+	})
+
+	t.Run("zero", func(t *T) {
+		var set TTLSet
+		requireEqual(true, set.Added(10))
+		requireEqual(false, set.Added(10))
+		requireEqual(true, set.Has(10))
+
+		expiry, ok := set.Expiry(10)
+		requireEqual(time.Time{}, expiry)
+		requireEqual(true, ok)
+		requireEqual(0, set.Expire())
+	})
+
+	t.Run("Added", func(t *T) {
+		set, clock, expired := newSet()
+
+		requireEqual(true, set.Added(20))
+		clock.advance(time.Second)
+		requireEqual(true, set.Added(10))
+		requireEqual(false, set.Added(20))
+		requireEqual([]interface{}{20, 10}, set.Values())
+
+		expiry, ok := set.Expiry(20)
+		requireEqual(time.Unix(1010, 0), expiry)
+		requireEqual(true, ok)
+
+		// The expired value is still stored, but invisible.
+		clock.advance(9 * time.Second)
+		requireEqual(false, set.Has(20))
+		requireEqual(true, set.Has(10))
+		requireEqual(0, len(*expired))
+
+		// Re-adding an expired value starts a new lifetime.
+		requireEqual(true, set.Added(20))
+		requireEqual([]interface{}{20}, *expired)
+		requireEqual([]interface{}{10, 20}, set.Values())
+	})
+
+	t.Run("AddedTTL", func(t *T) {
+		set, clock, expired := newSet()
+
+		set.Add(10)
+		set.AddTTL(20, 5*time.Second)
+		set.AddTTL(30, 0)
+		set.AddTTL(40, 20*time.Second)
+		set.AddTTL(50, 5*time.Second)
+		requireEqual([]interface{}{20, 50, 10, 40, 30}, set.Values())
+
+		clock.advance(5 * time.Second)
+		requireEqual(3, set.Len())
+		requireEqual([]interface{}{20, 50}, *expired)
+
+		clock.advance(time.Hour)
+		requireEqual(2, set.Expire())
+		requireEqual([]interface{}{20, 50, 10, 40}, *expired)
+		requireEqual([]interface{}{30}, set.Values())
+	})
+
+	t.Run("Deleted", func(t *T) {
+		set, clock, expired := newSet()
+
+		set.Add(10)
+		set.Add(20)
+		requireEqual(true, set.Deleted(10))
+		requireEqual(false, set.Deleted(10))
+		requireEqual([]interface{}{20}, set.Values())
+
+		clock.advance(time.Minute)
+		requireEqual(false, set.Deleted(20))
+		requireEqual([]interface{}{20}, *expired)
+	})
+
+	t.Run("OnExpire", func(t *T) {
+		set, clock, _ := newSet()
+
+		// The callback runs outside the lock, so it may use the set.
+		var readded []interface{}
+		set.OnExpire = func(val interface{}) {
+			if set.Added(val.(int) + 1) {
+				readded = append(readded, val)
+			}
+		}
+
+		set.Add(10)
+		set.Add(20)
+		clock.advance(time.Minute)
+		requireEqual(2, set.Expire())
+		requireEqual([]interface{}{10, 20}, readded)
+		requireEqual([]interface{}{11, 21}, set.Values())
+	})
+
+	t.Run("Janitor", func(t *T) {
+		set, clock, _ := newSet()
+
+		done := make(chan interface{}, 1)
+		set.OnExpire = func(val interface{}) { done <- val }
+
+		set.Add(10)
+		clock.advance(time.Minute)
+
+		janitor := set.Janitor(time.Millisecond)
+		defer janitor.Stop()
+
+		select {
+		case val := <-done:
+			requireEqual(10, val)
+		case <-time.After(time.Second * 5):
+			panic(`janitor didn't expire the value`)
+		}
+
+		janitor.Stop()
+		janitor.Stop()
+
+		requirePanic(func() { set.Janitor(0) })
+		requirePanic(func() { set.Janitor(-time.Second) })
+	})
+
+	t.Run("concurrent", func(t *T) {
+		set, clock, _ := newSet()
+		set.OnExpire = nil
+
+		var wg sync.WaitGroup
+		for i := range counter(8) {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for j := range counter(128) {
+					set.Add(i*128 + j)
+					_ = set.Has(j)
+					clock.advance(time.Millisecond * 100)
+					_ = set.Len()
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		clock.advance(time.Minute)
+		requireEqual(0, set.Len())
+	})
+}
+
+func TestFreqSet(t *T) {
+	t.Run("nil", func(t *T) {
+		var set *FreqSet
+		requireEqual(0, set.Len())
+		requireEqual(false, set.Has(10))
+		requireEqual(0, set.Count(10))
+		requireEqual(false, set.Touched(10))
+		requireEqual(false, set.Deleted(10))
+		requireEqual([]interface{}{}, set.Values())
+		requireEqual([]interface{}{}, set.ValuesAsc())
+
+		_, _, ok := set.Least()
+		requireEqual(false, ok)
+	})
+
+	t.Run("Added", func(t *T) {
+		var set FreqSet
+
+		requireEqual(true, set.Added(20))
+		requireEqual(true, set.Added(10))
+		requireEqual(true, set.Added(30))
+		requireEqual([]interface{}{20, 10, 30}, set.Values())
+
+		requireEqual(false, set.Added(30))
+		requireEqual(false, set.Added(10))
+		requireEqual(2, set.Count(30))
+		requireEqual(1, set.Count(20))
+		requireEqual(0, set.Count(40))
+		requireEqual([]interface{}{30, 10, 20}, set.Values())
+		requireEqual([]interface{}{20, 30, 10}, set.ValuesAsc())
+
+		set.Add(10)
+		requireEqual([]interface{}{10, 30, 20}, set.Values())
+		requireEqual(3, set.Len())
+		requireEqual(true, set.Has(20))
+	})
+
+	t.Run("Touched", func(t *T) {
+		var set FreqSet
+
+		requireEqual(false, set.Touched(10))
+		requireEqual(false, set.Has(10))
+
+		set.Add(10)
+		set.Add(20)
+		requireEqual(true, set.Touched(20))
+		requireEqual(2, set.Count(20))
+		requireEqual([]interface{}{20, 10}, set.Values())
+
+		// Ties break by when the count was reached.
+		set.Add(30)
+		set.Touch(30)
+		set.Touch(10)
+		requireEqual([]interface{}{20, 30, 10}, set.Values())
+		set.Touch(30)
+		set.Touch(20)
+		requireEqual([]interface{}{30, 20, 10}, set.Values())
+		requireEqual(nil, set.Validate())
+	})
+
+	t.Run("Deleted", func(t *T) {
+		var set FreqSet
+
+		set.Add(10)
+		set.Add(20)
+		set.Add(20)
+		requireEqual(true, set.Deleted(20))
+		requireEqual(false, set.Deleted(20))
+		requireEqual([]interface{}{10}, set.Values())
+
+		// The count starts over.
+		set.Add(20)
+		requireEqual(1, set.Count(20))
+		requireEqual([]interface{}{10, 20}, set.Values())
+	})
+
+	t.Run("Least and Most", func(t *T) {
+		var set FreqSet
+
+		set.Add(10)
+		set.Add(20)
+		set.Add(30)
+		set.Touch(20)
+		set.Touch(30)
+
+		val, count, ok := set.Least()
+		requireEqual([]interface{}{10, 1, true}, []interface{}{val, count, ok})
+
+		val, count, ok = set.Most()
+		requireEqual([]interface{}{20, 2, true}, []interface{}{val, count, ok})
+
+		val, count, ok = set.PoppedLeast()
+		requireEqual([]interface{}{10, 1, true}, []interface{}{val, count, ok})
+
+		val, count, ok = set.PoppedLeast()
+		requireEqual([]interface{}{20, 2, true}, []interface{}{val, count, ok})
+		requireEqual([]interface{}{30}, set.Values())
+	})
+
+	t.Run("Cap", func(t *T) {
+		type counted struct {
+			val   interface{}
+			count int
+		}
+
+		var evicted []interface{}
+		set := FreqSet{Cap: 3, OnEvict: func(val interface{}, count int) {
+			evicted = append(evicted, counted{val, count})
+		}}
+
+		set.Add(10)
+		set.Add(20)
+		set.Add(30)
+		set.Add(10)
+		set.Add(30)
+		requireEqual(0, len(evicted))
+
+		requireEqual(true, set.Added(40))
+		requireEqual([]interface{}{counted{20, 1}}, evicted)
+		requireEqual([]interface{}{10, 30, 40}, set.Values())
+
+		requireEqual(true, set.Added(50))
+		requireEqual([]interface{}{counted{20, 1}, counted{40, 1}}, evicted)
+		requireEqual([]interface{}{10, 30, 50}, set.Values())
+
+		// Incrementing never evicts.
+		requireEqual(false, set.Added(50))
+		requireEqual(2, len(evicted))
+
+		set.Cap = 1
+		set.Add(60)
+		requireEqual([]interface{}{counted{20, 1}, counted{40, 1}, counted{10, 2}, counted{30, 2}, counted{50, 2}}, evicted)
+		requireEqual([]interface{}{60}, set.Values())
+	})
+
+	t.Run("random", func(t *T) {
+		var set FreqSet
+		counts := map[interface{}]int{}
+		seqs := map[interface{}]int{}
+
+		for i := range counter(4096) {
+			val := rnd.Intn(64)
+			switch rnd.Intn(4) {
+			case 0, 1:
+				set.Add(val)
+				counts[val]++
+				seqs[val] = i
+			case 2:
+				if set.Touched(val) {
+					counts[val]++
+					seqs[val] = i
+				}
+			case 3:
+				set.Delete(val)
+				delete(counts, val)
+			}
+		}
+
+		requireEqual(len(counts), set.Len())
+		requireEqual(nil, set.Validate())
+		prev, prevSeq := 0, -1
+		for _, val := range set.ValuesAsc() {
+			requireEqual(counts[val], set.Count(val))
+			requireEqual(true, counts[val] > prev || counts[val] == prev && seqs[val] > prevSeq)
+			prev, prevSeq = counts[val], seqs[val]
+		}
+	})
+}
+
+func TestScoredSet(t *T) {
+	var _ Set = new(ScoredSet)
+
+	t.Run("nil", func(t *T) {
+		var set *ScoredSet
+		requireEqual(0, set.Len())
+		requireEqual(false, set.Has(10))
+		requireEqual(false, set.Deleted(10))
+		requireEqual([]interface{}{}, set.Values())
+		requireEqual([]interface{}{}, set.RangeByRank(0, 10))
+		requireEqual([]interface{}{}, set.RangeByScore(0, 10))
+
+		rank, ok := set.Rank(10)
+		requireEqual(pair{-1, false}, pair{rank, ok})
+
+		_, _, ok = set.PoppedMin()
+		requireEqual(false, ok)
+	})
+
+	t.Run("Set", func(t *T) {
+		var set ScoredSet
+
+		requireEqual(true, set.Added(20))
+		requireEqual(false, set.Added(20))
+		requireEqual(true, set.Added(10))
+		requireEqual([]interface{}{20, 10}, set.Values())
+
+		score, ok := set.Score(10)
+		requireEqual(0.0, score)
+		requireEqual(true, ok)
+
+		requireEqual(true, set.Deleted(20))
+		requireEqual(false, set.Deleted(20))
+		requireEqual([]interface{}{10}, set.Values())
+		requireEqual(1, set.Len())
+	})
+
+	t.Run("AddedWithScore", func(t *T) {
+		var set ScoredSet
+
+		requireEqual(true, set.AddedWithScore(`c`, 3))
+		requireEqual(true, set.AddedWithScore(`a`, 1))
+		requireEqual(true, set.AddedWithScore(`b`, 2))
+		requireEqual(true, set.AddedWithScore(`d`, 2))
+		requireEqual([]interface{}{`a`, `b`, `d`, `c`}, set.Values())
+
+		requireEqual(false, set.AddedWithScore(`a`, 2.5))
+		requireEqual([]interface{}{`b`, `d`, `a`, `c`}, set.Values())
+
+		// Keeps the position among equals.
+		requireEqual(false, set.AddedWithScore(`b`, 0))
+		requireEqual(false, set.AddedWithScore(`b`, 2))
+		requireEqual([]interface{}{`b`, `d`, `a`, `c`}, set.Values())
+
+		score, ok := set.Score(`a`)
+		requireEqual(2.5, score)
+		requireEqual(true, ok)
+
+		_, ok = set.Score(`e`)
+		requireEqual(false, ok)
+	})
+
+	t.Run("IncrScore", func(t *T) {
+		var set ScoredSet
+
+		requireEqual(2.0, set.IncrScore(`a`, 2))
+		requireEqual(1.0, set.IncrScore(`b`, 1))
+		requireEqual(3.0, set.IncrScore(`b`, 2))
+		requireEqual(-1.0, set.IncrScore(`a`, -3))
+		requireEqual([]interface{}{`a`, `b`}, set.Values())
+	})
+
+	t.Run("Rank", func(t *T) {
+		var set ScoredSet
+		set.AddWithScore(`a`, 1)
+		set.AddWithScore(`b`, 1)
+		set.AddWithScore(`c`, -1)
+
+		for i, val := range []interface{}{`c`, `a`, `b`} {
+			rank, ok := set.Rank(val)
+			requireEqual(pair{i, true}, pair{rank, ok})
+		}
+
+		rank, ok := set.Rank(`d`)
+		requireEqual(pair{-1, false}, pair{rank, ok})
+	})
+
+	t.Run("RangeByRank", func(t *T) {
+		var set ScoredSet
+		for i := range counter(5) {
+			set.AddWithScore(i, float64(10-i))
+		}
+
+		requireEqual([]interface{}{4, 3, 2, 1, 0}, set.RangeByRank(0, 5))
+		requireEqual([]interface{}{3, 2}, set.RangeByRank(1, 3))
+		requireEqual([]interface{}{1, 0}, set.RangeByRank(3, 10))
+		requireEqual([]interface{}{4}, set.RangeByRank(-5, 1))
+		requireEqual([]interface{}{}, set.RangeByRank(3, 3))
+		requireEqual([]interface{}{}, set.RangeByRank(4, 2))
+	})
+
+	t.Run("RangeByScore", func(t *T) {
+		var set ScoredSet
+		set.AddWithScore(`a`, 1)
+		set.AddWithScore(`b`, 2)
+		set.AddWithScore(`c`, 2)
+		set.AddWithScore(`d`, 3)
+
+		requireEqual([]interface{}{`b`, `c`}, set.RangeByScore(2, 2))
+		requireEqual([]interface{}{`a`, `b`, `c`}, set.RangeByScore(0, 2.5))
+		requireEqual([]interface{}{`b`, `c`, `d`}, set.RangeByScore(1.5, 10))
+		requireEqual([]interface{}{}, set.RangeByScore(3.5, 10))
+		requireEqual([]interface{}{}, set.RangeByScore(3, 1))
+		requireEqual([]interface{}{`a`, `b`, `c`, `d`}, set.RangeByScore(math.Inf(-1), math.Inf(1)))
+	})
+
+	t.Run("Popped", func(t *T) {
+		var set ScoredSet
+		set.AddWithScore(`a`, 2)
+		set.AddWithScore(`b`, 1)
+		set.AddWithScore(`c`, 2)
+
+		val, score, ok := set.PoppedMin()
+		requireEqual([]interface{}{`b`, 1.0, true}, []interface{}{val, score, ok})
+
+		val, score, ok = set.PoppedMax()
+		requireEqual([]interface{}{`c`, 2.0, true}, []interface{}{val, score, ok})
+
+		val, score, ok = set.PoppedMax()
+		requireEqual([]interface{}{`a`, 2.0, true}, []interface{}{val, score, ok})
+
+		_, _, ok = set.PoppedMax()
+		requireEqual(false, ok)
+		requireEqual(0, set.Len())
+
+		set.Add(`d`)
+		requireEqual([]interface{}{`d`}, set.Values())
+	})
+
+	t.Run("NaN", func(t *T) {
+		var set ScoredSet
+		requirePanic(func() { set.AddWithScore(`a`, math.NaN()) })
+		requirePanic(func() { set.IncrScore(`a`, math.NaN()) })
+		requireEqual(0, set.Len())
+	})
+
+	t.Run("random", func(t *T) {
+		var set ScoredSet
+		scores := map[interface{}]float64{}
+		seqs := map[interface{}]int{}
+
+		for i := range counter(4096) {
+			val := rnd.Intn(256)
+			score := float64(rnd.Intn(16))
+
+			switch rnd.Intn(5) {
+			case 0, 1:
+				if set.AddedWithScore(val, score) {
+					seqs[val] = i
+				}
+				scores[val] = score
+			case 2:
+				if !set.Has(val) {
+					seqs[val] = i
+				}
+				scores[val] = set.IncrScore(val, score-8)
+			case 3:
+				set.Delete(val)
+				delete(scores, val)
+			case 4:
+				val, _, ok := set.PoppedMin()
+				if ok {
+					delete(scores, val)
+				}
+			}
+		}
+
+		var expected []interface{}
+		for val := range scores {
+			expected = append(expected, val)
+		}
+		sort.Slice(expected, func(i, j int) bool {
+			one, other := expected[i], expected[j]
+			if scores[one] != scores[other] {
+				return scores[one] < scores[other]
+			}
+			return seqs[one] < seqs[other]
+		})
+
+		requireEqual(len(expected), set.Len())
+		requireEqual(expected, set.Values())
+		for i, val := range expected {
+			rank, _ := set.Rank(val)
+			requireEqual(i, rank)
+			requireEqual(expected[i:i+1], set.RangeByRank(i, i+1))
+		}
+		requireEqual(expected[len(expected)/3:len(expected)/2], set.RangeByRank(len(expected)/3, len(expected)/2))
+
+		var between []interface{}
+		for _, val := range expected {
+			if scores[val] >= 3 && scores[val] <= 9 {
+				between = append(between, val)
+			}
+		}
+		requireEqual(between, set.RangeByScore(3, 9))
+	})
+}
+
+func TestOrdBag(t *T) {
+	testOrdBag(t, func() ordBag { return new(OrdBag) })
+
+	t.Run("nil", func(t *T) {
+		var bag *OrdBag
+		requireEqual(0, bag.Len())
+		requireEqual(0, bag.Total())
+		requireEqual(0, bag.Count(10))
+		requireEqual(false, bag.Removed(10))
+		requireEqual([]BagEntry{}, bag.Entries())
+		requireEqual(`[]`, bag.String())
+	})
+
+	t.Run("algebra", func(t *T) {
+		one := NewOrdBag(`a`, `b`, `a`, `c`, `a`)
+		other := NewOrdBag(`d`, `c`, `c`, `a`, `c`, `d`)
+
+		requireEqual(
+			[]BagEntry{{`a`, 4}, {`b`, 1}, {`c`, 4}, {`d`, 2}},
+			one.Sum(other).Entries(),
+		)
+		requireEqual(
+			[]BagEntry{{`a`, 3}, {`b`, 1}, {`c`, 3}, {`d`, 2}},
+			one.Union(other).Entries(),
+		)
+		requireEqual(
+			[]BagEntry{{`a`, 1}, {`c`, 1}},
+			one.Intersection(other).Entries(),
+		)
+		requireEqual(
+			[]BagEntry{{`c`, 1}, {`a`, 1}},
+			other.Intersection(one).Entries(),
+		)
+		requireEqual(one.Entries(), one.Union(nil).Entries())
+		requireEqual([]BagEntry{}, one.Intersection(nil).Entries())
+		requireEqual(one.Total()+other.Total(), one.Sum(other).Total())
+
+		// The operands are unaffected.
+		requireEqual([]BagEntry{{`a`, 3}, {`b`, 1}, {`c`, 1}}, one.Entries())
+
+		clone := one.Copy()
+		clone.Add(`b`)
+		requireEqual(1, one.Count(`b`))
+		requireEqual(2, clone.Count(`b`))
+	})
+}
+
+func TestSyncOrdBag(t *T) {
+	testOrdBag(t, func() ordBag { return new(SyncOrdBag) })
+
+	t.Run("algebra", func(t *T) {
+		one := NewSyncOrdBag(`a`, `b`, `a`)
+		other := NewSyncOrdBag(`c`, `a`)
+
+		requireEqual([]BagEntry{{`a`, 3}, {`b`, 1}, {`c`, 1}}, one.Sum(other).Entries())
+		requireEqual([]BagEntry{{`a`, 2}, {`b`, 1}, {`c`, 1}}, one.Union(other).Entries())
+		requireEqual([]BagEntry{{`a`, 1}}, one.Intersection(other).Entries())
+
+		// Combining a bag with itself must not deadlock.
+		requireEqual([]BagEntry{{`a`, 4}, {`b`, 2}}, one.Sum(one).Entries())
+	})
+
+	t.Run("concurrent", func(t *T) {
+		var bag SyncOrdBag
+		var wg sync.WaitGroup
+
+		for range counter(8) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range counter(256) {
+					bag.Add(i % 16)
+				}
+				for i := range counter(128) {
+					bag.Remove(i % 16)
+				}
+			}()
+		}
+		wg.Wait()
+
+		requireEqual(16, bag.Len())
+		requireEqual(8*128, bag.Total())
+		requireEqual(64, bag.Count(0))
+	})
+}
+
+// Methods shared by `OrdBag` and `SyncOrdBag`.
+type ordBag interface {
+	Set
+	Total() int
+	Count(interface{}) int
+	AddN(interface{}, int)
+	Removed(interface{}) bool
+	RemovedN(interface{}, int) int
+	DeletedAll(interface{}) int
+	Values() []interface{}
+	Entries() []BagEntry
+	Each(func(interface{}, int))
+	String() string
+}
+
+func testOrdBag(t *T, newBag func() ordBag) {
+	t.Run("Add", func(t *T) {
+		bag := newBag()
+
+		bag.Add(20)
+		bag.Add(10)
+		bag.Add(20)
+		bag.AddN(30, 3)
+		bag.AddN(10, 0)
+		bag.AddN(40, -1)
+
+		requireEqual(3, bag.Len())
+		requireEqual(6, bag.Total())
+		requireEqual(2, bag.Count(20))
+		requireEqual(1, bag.Count(10))
+		requireEqual(3, bag.Count(30))
+		requireEqual(0, bag.Count(40))
+		requireEqual(true, bag.Has(30))
+		requireEqual(false, bag.Has(40))
+		requireEqual([]interface{}{20, 10, 30}, bag.Values())
+		requireEqual([]BagEntry{{20, 2}, {10, 1}, {30, 3}}, bag.Entries())
+		requireEqual(`[20:2 10:1 30:3]`, bag.String())
+	})
+
+	t.Run("Removed", func(t *T) {
+		bag := newBag()
+		bag.AddN(20, 2)
+		bag.Add(10)
+
+		requireEqual(true, bag.Removed(20))
+		requireEqual(1, bag.Count(20))
+		requireEqual([]interface{}{20, 10}, bag.Values())
+
+		requireEqual(true, bag.Removed(20))
+		requireEqual(false, bag.Has(20))
+		requireEqual(false, bag.Removed(20))
+		requireEqual([]interface{}{10}, bag.Values())
+		requireEqual(1, bag.Total())
+
+		// Re-adding goes to the end.
+		bag.Add(20)
+		requireEqual([]interface{}{10, 20}, bag.Values())
+	})
+
+	t.Run("RemovedN", func(t *T) {
+		bag := newBag()
+		bag.AddN(20, 5)
+		bag.Add(10)
+
+		requireEqual(0, bag.RemovedN(20, 0))
+		requireEqual(2, bag.RemovedN(20, 2))
+		requireEqual(3, bag.RemovedN(20, 10))
+		requireEqual(0, bag.RemovedN(20, 1))
+		requireEqual(1, bag.Total())
+		requireEqual([]interface{}{10}, bag.Values())
+	})
+
+	t.Run("Deleted", func(t *T) {
+		bag := newBag()
+		bag.AddN(20, 5)
+		bag.Add(10)
+
+		requireEqual(5, bag.DeletedAll(20))
+		requireEqual(0, bag.DeletedAll(20))
+		requireEqual(1, bag.Total())
+		requireEqual([]BagEntry{{10, 1}}, bag.Entries())
+
+		bag.AddN(30, 2)
+		requireEqual(true, bag.Deleted(30))
+		requireEqual(false, bag.Deleted(30))
+		requireEqual(1, bag.Total())
+	})
+
+	t.Run("Added", func(t *T) {
+		bag := newBag()
+		requireEqual(true, bag.Added(20))
+		requireEqual(false, bag.Added(20))
+		requireEqual(true, bag.Added(10))
+		requireEqual([]BagEntry{{20, 2}, {10, 1}}, bag.Entries())
+	})
+
+	t.Run("Each", func(t *T) {
+		bag := newBag()
+		bag.Add(20)
+		bag.AddN(10, 2)
+
+		var entries []BagEntry
+		bag.Each(func(val interface{}, count int) {
+			entries = append(entries, BagEntry{val, count})
+		})
+		requireEqual([]BagEntry{{20, 1}, {10, 2}}, entries)
+	})
+}
+
+func TestTypedLinkedSet(t *T) {
+	t.Run("nil", func(t *T) {
+		var set *LinkedIntSet
+		requireEqual(0, set.Len())
+		requireEqual(false, set.Has(0))
+		requireEqual(false, set.Deleted(0))
+		requireEqual([]int{}, set.Values())
+
+		val, ok := set.PoppedFirst()
+		requireEqual(pair{0, false}, pair{val, ok})
+
+		val, ok = set.Next(0)
+		requireEqual(pair{0, false}, pair{val, ok})
+	})
+
+	t.Run("ints", func(t *T) {
+		var set LinkedIntSet
+
+		requireEqual(true, set.Added(20))
+		requireEqual(true, set.Added(10))
+		requireEqual(false, set.Added(20))
+		requireEqual(true, set.AddedLast(30))
+		requireEqual(true, set.AddedFirst(0))
+		requireEqual([]int{0, 20, 10, 30}, set.Values())
+		requireEqual(4, set.Len())
+
+		requireEqual(false, set.AddedLast(0))
+		requireEqual(false, set.AddedFirst(10))
+		requireEqual([]int{10, 20, 30, 0}, set.Values())
+
+		val, ok := set.Next(20)
+		requireEqual(pair{30, true}, pair{val, ok})
+		val, ok = set.Prev(20)
+		requireEqual(pair{10, true}, pair{val, ok})
+		val, ok = set.Prev(10)
+		requireEqual(pair{0, false}, pair{val, ok})
+
+		val, ok = set.First()
+		requireEqual(pair{10, true}, pair{val, ok})
+		val, ok = set.Last()
+		requireEqual(pair{0, true}, pair{val, ok})
+
+		requireEqual(true, set.Deleted(20))
+		requireEqual(false, set.Deleted(20))
+		requireEqual([]int{10, 30, 0}, set.Values())
+
+		val, ok = set.PoppedFirst()
+		requireEqual(pair{10, true}, pair{val, ok})
+		val, ok = set.PoppedLast()
+		requireEqual(pair{0, true}, pair{val, ok})
+		val, ok = set.PoppedLast()
+		requireEqual(pair{30, true}, pair{val, ok})
+		val, ok = set.PoppedLast()
+		requireEqual(pair{0, false}, pair{val, ok})
+
+		set.Add(40)
+		requireEqual([]int{40}, set.Values())
+		requireEqual(`[40]`, set.String())
+	})
+
+	t.Run("strings", func(t *T) {
+		set := NewLinkedStringSet(`b`, `a`, `b`, `c`)
+		requireEqual([]string{`b`, `a`, `c`}, set.Values())
+		requireEqual(true, set.Has(`a`))
+		requireEqual(false, set.Has(``))
+	})
+
+	t.Run("OrdSet", func(t *T) {
+		set := NewLinkedIntSet(20, 10)
+		ord := set.OrdSet()
+
+		ord.AddFirst(30)
+		requireEqual([]int{30, 20, 10}, set.Values())
+		requireEqual([]interface{}{30, 20, 10}, ord.Values())
+		requireEqual(false, ord.Has(`30`))
+		requireEqual(false, ord.Deleted(int64(30)))
+
+		val, ok := ord.Next(`20`)
+		requireEqual(pair{nil, false}, pair{val, ok})
+
+		val, ok = ord.PoppedLast()
+		requireEqual(pair{10, true}, pair{val, ok})
+		requireEqual([]int{30, 20}, set.Values())
+
+		requirePanic(func() { ord.Add(`40`) })
+	})
+
+	t.Run("matches LinkedSet", func(t *T) {
+		var typed LinkedIntSet
+		var ref LinkedSet
+
+		for range counter(4096) {
+			val := rnd.Intn(32)
+			switch rnd.Intn(6) {
+			case 0:
+				requireEqual(ref.Added(val), typed.Added(val))
+			case 1:
+				requireEqual(ref.AddedFirst(val), typed.AddedFirst(val))
+			case 2:
+				requireEqual(ref.AddedLast(val), typed.AddedLast(val))
+			case 3:
+				requireEqual(ref.Deleted(val), typed.Deleted(val))
+			case 4:
+				refVal, refOk := ref.PoppedFirst()
+				val, ok := typed.PoppedFirst()
+				requireEqual(refOk, ok)
+				if ok {
+					requireEqual(refVal, val)
+				}
+			case 5:
+				refVal, refOk := ref.PoppedLast()
+				val, ok := typed.PoppedLast()
+				requireEqual(refOk, ok)
+				if ok {
+					requireEqual(refVal, val)
+				}
+			}
+			requireEqual(ref.Values(), typed.OrdSet().Values())
+		}
+	})
+}
+
+func TestStats(t *T) {
+	sets := []StatsSet{
+		new(LinkedSet), new(SyncLinkedSet), new(SliceSet), new(SyncSliceSet),
+		new(CowSliceSet), new(LinkedIntSet), new(OrdBag), new(SyncOrdBag),
+		new(TTLSet), new(FreqSet), new(ScoredSet), NewCountingSet(new(LinkedSet)),
+	}
+
+	for _, set := range sets {
+		requireEqual(Stats{}, set.Stats())
+
+		for i := range counter(100) {
+			switch set := set.(type) {
+			case Set:
+				set.Add(i)
+			case *OrdBag:
+				set.Add(i)
+			case *SyncOrdBag:
+				set.Add(i)
+			case *LinkedIntSet:
+				set.Add(i)
+			}
+		}
+
+		stats := set.Stats()
+		requireEqual(100, stats.Len)
+		requireEqual(true, stats.Cap >= stats.Len)
+		requireEqual(true, stats.Bytes() >= 100*sizeofPtr)
+		requireEqual(stats.MapBytes+stats.NodeBytes+stats.SliceBytes, stats.Bytes())
+	}
+
+	t.Run("SliceSet", func(t *T) {
+		set := make(SliceSet, 0, 16)
+		set.Add(10)
+		requireEqual(Stats{Len: 1, Cap: 16, SliceBytes: 16 * sizeofAny}, set.Stats())
+	})
+
+	t.Run("LinkedSet", func(t *T) {
+		stats := NewLinkedSet(10, 20, 30).Stats()
+		requireEqual(0, stats.SliceBytes)
+		requireEqual(3*sizeofElem, stats.NodeBytes)
+		requireEqual(mapBytes(3, sizeofAny, sizeofPtr), stats.MapBytes)
+	})
+
+	t.Run("mapBytes", func(t *T) {
+		requireEqual(0, mapBytes(0, 16, 8))
+		requireEqual(8*25, mapBytes(1, 16, 8))
+		requireEqual(8*25, mapBytes(6, 16, 8))
+		requireEqual(2*8*25, mapBytes(7, 16, 8))
+		requireEqual(true, mapBytes(1000, 16, 8) >= 1000*25)
+	})
+
+	t.Run("LockWaits", func(t *T) {
+		var set SyncLinkedSet
+		requireEqual(uint64(0), set.Stats().LockWaits)
+
+		locked := make(chan struct{})
+		release := make(chan struct{})
+		go set.Update(func(*LinkedSet) {
+			close(locked)
+			<-release
+		})
+		<-locked
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			set.Add(20)
+		}()
+
+		// Give the goroutine time to block on the lock.
+		time.Sleep(time.Millisecond * 10)
+		close(release)
+		<-done
+
+		stats := set.Stats()
+		requireEqual(1, stats.Len)
+		requireEqual(true, stats.LockWaits >= 1)
+		requireEqual(true, stats.LockWaitTime > 0)
+	})
+
+	// On 32-bit platforms, 64-bit atomics panic unless 8-byte aligned, which
+	// isn't guaranteed for fields after the first. Run with `GOARCH=386`.
+	t.Run("offset", func(t *T) {
+		var outer struct {
+			_        uint32
+			set      SyncLinkedSet
+			_        uint32
+			counting CountingSet
+		}
+
+		outer.set.lock.waits.add(1)
+		outer.set.lock.waitNanos.add(2)
+		stats := outer.set.Stats()
+		requireEqual(uint64(1), stats.LockWaits)
+		requireEqual(time.Duration(2), stats.LockWaitTime)
+
+		outer.counting.OrdSet = new(LinkedSet)
+		outer.counting.Add(10)
+		requireEqual(OpStats{Adds: 1}, outer.counting.Ops())
+	})
+}
+
+func TestCountingSet(t *T) {
+	set := NewCountingSet(new(SyncLinkedSet))
+	requireEqual(OpStats{}, set.Ops())
+
+	set.Add(10)
+	set.Add(20)
+	set.Add(10)
+	set.AddFirst(20)
+	set.AddLast(30)
+	requireEqual(true, set.AddedLast(40))
+	set.Delete(10)
+	requireEqual(false, set.Deleted(50))
+
+	val, ok := set.PoppedFirst()
+	requireEqual(pair{20, true}, pair{val, ok})
+	val, ok = set.PoppedLast()
+	requireEqual(pair{40, true}, pair{val, ok})
+
+	requireEqual([]interface{}{30}, set.Values())
+	requireEqual(OpStats{Adds: 4, Deletes: 1, Moves: 1, Pops: 2}, set.Ops())
+
+	stats := set.Stats()
+	requireEqual(1, stats.Len)
+	requireEqual(set.Ops(), stats.Ops)
+
+	_, _ = set.PoppedFirst()
+	_, _ = set.PoppedFirst()
+	requireEqual(uint64(3), set.Ops().Pops)
+
+	// Sets without `.Stats` still report length and operations.
+	other := NewCountingSet(struct{ OrdSet }{new(LinkedSet)})
+	other.Add(10)
+	requireEqual(Stats{Len: 1, Cap: 1, Ops: OpStats{Adds: 1}}, other.Stats())
+
+	t.Run("concurrent", func(t *T) {
+		set := NewCountingSet(new(SyncLinkedSet))
+		var wg sync.WaitGroup
+		for i := range counter(8) {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for j := range counter(64) {
+					set.Add(i*64 + j)
+				}
+			}(i)
+		}
+		wg.Wait()
+		requireEqual(uint64(8*64), set.Ops().Adds)
+	})
+}
+
+func TestValidate(t *T) {
+	sets := []interface{ Validate() error }{
+		new(LinkedSet), new(SyncLinkedSet), new(SliceSet), new(SyncSliceSet),
+		new(CowSliceSet), new(LinkedIntSet), new(OrdBag), new(SyncOrdBag),
+		new(TTLSet), new(FreqSet), new(ScoredSet), NewCountingSet(new(LinkedSet)),
+	}
+
+	for _, set := range sets {
+		requireEqual(nil, set.Validate())
+
+		for range counter(200) {
+			val := rnd.Intn(50)
+			switch set := set.(type) {
+			case *OrdBag:
+				set.Add(val)
+				set.Remove(rnd.Intn(50))
+			case *SyncOrdBag:
+				set.Add(val)
+				set.Remove(rnd.Intn(50))
+			case *LinkedIntSet:
+				set.AddFirst(val)
+				set.Delete(rnd.Intn(50))
+			case *ScoredSet:
+				set.AddWithScore(val, float64(rnd.Intn(10)))
+				set.Delete(rnd.Intn(50))
+			case OrdSet:
+				set.AddFirst(val)
+				set.Delete(rnd.Intn(50))
+			case Set:
+				set.Add(val)
+				set.Delete(rnd.Intn(50))
+			}
+		}
+
+		requireEqual(nil, set.Validate())
+	}
+
+	t.Run("LinkedSet copied", func(t *T) {
+		set := NewLinkedSet(10, 20)
+		other := *set
+		other.debug = debugState{} // Bypass the `gord_debug` copy check.
+		other.Add(30)
+		requireInvalid(set.Validate(), `map has 3 entries, list has 2 nodes`)
+	})
+
+	t.Run("LinkedSet missing from map", func(t *T) {
+		set := NewLinkedSet(10, 20, 30)
+		delete(set.set, 20)
+		set.set[40] = set.ord.Front()
+		requireInvalid(set.Validate(), `value 20 at index 1 is missing from the map`)
+	})
+
+	t.Run("LinkedSet duplicate", func(t *T) {
+		set := NewLinkedSet(10, 20, 30)
+		set.ord.Back().Value = 10
+		delete(set.set, 30)
+		set.set[40] = set.ord.Back()
+		requireInvalid(set.Validate(), `value 10 at index 2 duplicates index 0`)
+	})
+
+	t.Run("LinkedSet fingerprint", func(t *T) {
+		set := NewLinkedSet(10, 20)
+		_ = set.Fingerprint()
+		requireEqual(nil, set.Validate())
+		set.hash++
+		requireInvalid(set.Validate(), `running fingerprint`)
+	})
+
+	t.Run("SliceSet duplicate", func(t *T) {
+		set := SliceSet{10, 20, 30, 20}
+		requireInvalid(set.Validate(), `value 20 at index 3 duplicates index 1`)
+	})
+
+	t.Run("TypedLinkedSet", func(t *T) {
+		set := NewLinkedIntSet(10, 20, 30)
+		set.last.prev = set.first
+		requireInvalid(set.Validate(), `index 2 with value 30 has a wrong backward link`)
+	})
+
+	t.Run("OrdBag", func(t *T) {
+		bag := NewOrdBag(10, 20, 20)
+		bag.total++
+		requireInvalid(bag.Validate(), `counts add up to 3, but the total is 4`)
+	})
+
+	t.Run("FreqSet", func(t *T) {
+		var set FreqSet
+		set.Add(10)
+		set.Add(20)
+		set.Touch(20)
+		bucket := set.buckets.Front().Value.(*freqBucket)
+		bucket.count = 5
+		requireInvalid(set.Validate(), `bucket with count 2 follows bucket with count 5`)
+	})
+
+	t.Run("ScoredSet", func(t *T) {
+		var set ScoredSet
+		for i := range counter(100) {
+			set.AddWithScore(i, float64(i))
+		}
+		requireEqual(nil, set.Validate())
+		set.head.next[0].span = 2
+		requireInvalid(set.Validate(), `link to value 0 on level 0 spans 2 nodes instead of 1`)
+	})
+}
+
+func requireInvalid(err error, msg string) {
+	if !errors.Is(err, ErrInvalid) || !strings.Contains(err.Error(), msg) {
+		panic(fmt.Errorf(`expected an invalid set error containing %q, got %v`, msg, err))
+	}
+}
+
+func TestWalSet(t *T) {
+	t.Run("replay", func(t *T) {
+		path := filepath.Join(t.TempDir(), `set.wal`)
+		set := openWal(path, WalOptions{})
+
+		for _, val := range []string{`one`, `two`, `three`, `four`} {
+			requireEqual(true, set.Added(val))
+		}
+		requireEqual(false, set.Added(`one`))
+		requireEqual(false, set.AddedFirst(`three`))
+		requireEqual(true, set.AddedLast(`five`))
+		requireEqual(true, set.Deleted(`two`))
+		requireEqual(false, set.Deleted(`two`))
+		requireEqual(pair{`three`, true}, toPair(set.PoppedFirst()))
+		requireEqual(pair{`five`, true}, toPair(set.PoppedLast()))
+
+		want := []interface{}{`one`, `four`}
+		requireEqual(want, set.Values())
+		requireEqual(nil, set.Close())
+		requireEqual(nil, set.Close())
+
+		requireEqual(false, set.Added(`six`))
+		requireEqual(want, set.Values())
+
+		set = openWal(path, WalOptions{})
+		requireEqual(want, set.Values())
+		requireEqual(nil, set.Validate())
+		requireEqual(nil, set.Close())
+	})
+
+	t.Run("JSONCodec", func(t *T) {
+		path := filepath.Join(t.TempDir(), `set.wal`)
+		set := openWal(path, WalOptions{})
+		set.Add(10.0)
+		set.Add(nil)
+		set.Add(true)
+
+		// Values that wouldn't replay as themselves are refused.
+		requireEqual(false, set.Added(10))
+		requireEqual(true, set.Err() != nil)
+		requireEqual(false, set.Added(struct{ A int }{1}))
+		requireEqual(true, set.Err() != nil)
+		requireEqual([]interface{}{10.0, nil, true}, set.Values())
+		requireEqual(nil, set.Close())
+
+		requireEqual([]interface{}{10.0, nil, true}, openWal(path, WalOptions{}).Values())
+
+		_, err := JSONCodec{}.Decode([]byte(`[10]`))
+		requireEqual(true, err != nil)
+	})
+
+	t.Run("Codec", func(t *T) {
+		path := filepath.Join(t.TempDir(), `set.wal`)
+		set := openWal(path, WalOptions{Codec: walIntCodec{}})
+		set.Add(10)
+		set.AddFirst(20)
+		requireEqual(nil, set.Close())
+
+		requireEqual([]interface{}{20, 10}, openWal(path, WalOptions{Codec: walIntCodec{}}).Values())
+
+		path = filepath.Join(t.TempDir(), `set.wal`)
+		set = openWal(path, WalOptions{})
+		set.Add(`one`)
+		requireEqual(nil, set.Close())
+
+		_, err := OpenWalSet(path, WalOptions{Codec: walIntCodec{}})
+		requireEqual(true, err != nil)
+	})
+
+	t.Run("torn tail", func(t *T) {
+		path := filepath.Join(t.TempDir(), `set.wal`)
+		set := openWal(path, WalOptions{Sync: WalSyncNever})
+		set.Add(`one`)
+		set.Add(`two`)
+		requireEqual(nil, set.Close())
+		size := fileSize(path)
+
+		// Partial record, as if the process crashed while appending it.
+		appendFile(path, []byte{20, 0, 0, 0, 1, 2, 3})
+
+		set = openWal(path, WalOptions{})
+		requireEqual([]interface{}{`one`, `two`}, set.Values())
+		requireEqual(size, fileSize(path))
+
+		set.Add(`three`)
+		requireEqual(nil, set.Close())
+		requireEqual([]interface{}{`one`, `two`, `three`}, openWal(path, WalOptions{}).Values())
+	})
+
+	t.Run("checksum", func(t *T) {
+		path := filepath.Join(t.TempDir(), `set.wal`)
+		set := openWal(path, WalOptions{})
+		set.Add(`one`)
+		set.Add(`two`)
+		set.Add(`three`)
+		requireEqual(nil, set.Close())
+
+		content, err := os.ReadFile(path)
+		try(err)
+		content[len(content)-2] ^= 1
+		try(os.WriteFile(path, content, 0o666))
+
+		requireEqual([]interface{}{`one`, `two`}, openWal(path, WalOptions{}).Values())
+	})
+
+	t.Run("corrupted middle", func(t *T) {
+		path := filepath.Join(t.TempDir(), `set.wal`)
+		set := openWal(path, WalOptions{})
+		set.Add(`one`)
+		set.Add(`two`)
+		set.Add(`three`)
+		requireEqual(nil, set.Close())
+
+		content, err := os.ReadFile(path)
+		try(err)
+		content[len(walMagic)+walHeaderSize+2] ^= 1
+		try(os.WriteFile(path, content, 0o666))
+
+		_, err = OpenWalSet(path, WalOptions{})
+		requireEqual(true, err != nil && strings.Contains(err.Error(), `corrupted WalSet record at offset 8`))
+
+		after, err := os.ReadFile(path)
+		try(err)
+		requireEqual(content, after)
+
+		// Same for an invalid length.
+		content[len(walMagic)] = 0
+		content[len(walMagic)+1] = 0
+		try(os.WriteFile(path, content, 0o666))
+		_, err = OpenWalSet(path, WalOptions{})
+		requireEqual(true, err != nil)
+		requireEqual(int64(len(content)), fileSize(path))
+	})
+
+	t.Run("zero tail", func(t *T) {
+		path := filepath.Join(t.TempDir(), `set.wal`)
+		set := openWal(path, WalOptions{})
+		set.Add(`one`)
+		requireEqual(nil, set.Close())
+		size := fileSize(path)
+
+		// Space allocated by the file system, but never written.
+		appendFile(path, make([]byte, 100))
+
+		requireEqual([]interface{}{`one`}, openWal(path, WalOptions{}).Values())
+		requireEqual(size, fileSize(path))
+	})
+
+	t.Run("torn header", func(t *T) {
+		path := filepath.Join(t.TempDir(), `set.wal`)
+		try(os.WriteFile(path, []byte(walMagic[:3]), 0o666))
+
+		set := openWal(path, WalOptions{})
+		requireEqual(0, set.Len())
+		set.Add(`one`)
+		requireEqual(nil, set.Close())
+		requireEqual([]interface{}{`one`}, openWal(path, WalOptions{}).Values())
+	})
+
+	t.Run("not a log", func(t *T) {
+		path := filepath.Join(t.TempDir(), `set.wal`)
+		try(os.WriteFile(path, []byte(`hello world`), 0o666))
+		_, err := OpenWalSet(path, WalOptions{})
+		requireEqual(true, err != nil)
+	})
+
+	t.Run("Compact", func(t *T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, `set.wal`)
+		set := openWal(path, WalOptions{Sync: WalSyncNever})
+		for i := range counter(100) {
+			set.Add(strconv.Itoa(i % 10))
+			set.Delete(strconv.Itoa(i % 7))
+		}
+		want := set.Values()
+		before := fileSize(path)
+
+		requireEqual(nil, set.Compact())
+		requireEqual(true, fileSize(path) < before)
+		requireEqual(want, set.Values())
+
+		set.Add(`last`)
+		want = append(want, `last`)
+		requireEqual(nil, set.Close())
+		requireEqual(want, openWal(path, WalOptions{}).Values())
+
+		entries, err := os.ReadDir(dir)
+		try(err)
+		requireEqual(1, len(entries))
+	})
+
+	t.Run("CompactAfter", func(t *T) {
+		path := filepath.Join(t.TempDir(), `set.wal`)
+		set := openWal(path, WalOptions{Codec: walIntCodec{}, Sync: WalSyncNever, CompactAfter: 16})
+		for i := range counter(1000) {
+			set.Add(i % 4)
+			set.Delete((i + 2) % 4)
+		}
+		want := set.Values()
+		requireEqual(true, fileSize(path) < 1024)
+		requireEqual(nil, set.Close())
+		requireEqual(want, openWal(path, WalOptions{Codec: walIntCodec{}}).Values())
+	})
+
+	t.Run("sticky error", func(t *T) {
+		path := filepath.Join(t.TempDir(), `set.wal`)
+		set := openWal(path, WalOptions{})
+		set.Add(`one`)
+
+		// Simulates a failing disk.
+		try(set.file.Close())
+
+		requireEqual(false, set.Added(`two`))
+		requireEqual([]interface{}{`one`}, set.Values())
+		requireEqual(true, set.Err() != nil)
+		requireEqual(false, set.Added(`three`))
+		requireEqual(set.Err(), set.Close())
+	})
+
+	t.Run("encoding error", func(t *T) {
+		path := filepath.Join(t.TempDir(), `set.wal`)
+		set := openWal(path, WalOptions{})
+
+		requireEqual(false, set.Added(math.Inf(1)))
+		requireEqual(true, set.Err() != nil)
+		requireEqual(false, set.Has(math.Inf(1)))
+
+		requireEqual(true, set.Added(`one`))
+		requireEqual(nil, set.Err())
+		requireEqual(nil, set.Close())
+		requireEqual([]interface{}{`one`}, openWal(path, WalOptions{}).Values())
+	})
+}
+
+func openWal(path string, opts WalOptions) *WalSet {
+	set, err := OpenWalSet(path, opts)
+	try(err)
+	return set
+}
+
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	try(err)
+	return info.Size()
+}
+
+func appendFile(path string, content []byte) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	try(err)
+	defer file.Close()
+	_, err = file.Write(content)
+	try(err)
+}
+
+type walIntCodec struct{}
+
+func (walIntCodec) Encode(val interface{}) ([]byte, error) {
+	return strconv.AppendInt(nil, int64(val.(int)), 10), nil
+}
+
+func (walIntCodec) Decode(src []byte) (interface{}, error) { return strconv.Atoi(string(src)) }
+
+func TestStream(t *T) {
+	vals := []interface{}{`one`, 2.0, true, nil, `with "quotes"`}
+
+	sets := []OrdSet{
+		NewLinkedSet(vals...), NewSyncLinkedSet(vals...), NewSliceSet(vals...),
+		NewSyncSliceSet(vals...), NewCowSliceSet(vals...), NewCountingSet(NewLinkedSet(vals...)),
+	}
+
+	for _, format := range []StreamFormat{{}, {Binary: true}} {
+		for _, set := range sets {
+			var buf bytes.Buffer
+			try(format.Encode(&buf, set))
+
+			out := new(LinkedSet)
+			try(format.Decode(&buf, out))
+			requireEqual(vals, out.Values())
+		}
+	}
+
+	t.Run("lines", func(t *T) {
+		var buf bytes.Buffer
+		try(Encode(&buf, NewLinkedSet(vals...)))
+		requireEqual("\"one\"\n2\ntrue\nnull\n\"with \\\"quotes\\\"\"\n", buf.String())
+
+		out := new(SliceSet)
+		try(Decode(strings.NewReader("\"one\"\r\n\n2\n\n\ntrue"), out))
+		requireEqual(SliceSet{`one`, 2.0, true}, *out)
+
+		try(Encode(&buf, nil))
+		try(Encode(&buf, (*LinkedSet)(nil)))
+	})
+
+	t.Run("binary", func(t *T) {
+		var buf bytes.Buffer
+		try(StreamFormat{Binary: true, Codec: walIntCodec{}}.Encode(&buf, NewLinkedSet(10, 200)))
+		requireEqual([]byte("\x0210\x03200"), buf.Bytes())
+
+		out := new(LinkedSet)
+		try(StreamFormat{Binary: true, Codec: walIntCodec{}}.Decode(&buf, out))
+		requireEqual([]interface{}{10, 200}, out.Values())
+
+		err := StreamFormat{Binary: true}.Decode(strings.NewReader("\x05one"), new(LinkedSet))
+		requireEqual(true, errors.Is(err, io.ErrUnexpectedEOF))
+	})
+
+	t.Run("newlines", func(t *T) {
+		set := NewLinkedSet("one\ntwo")
+		var buf bytes.Buffer
+		requireEqual(true, StreamFormat{Codec: streamRawCodec{}}.Encode(&buf, set) != nil)
+		requireEqual(true, StreamFormat{Codec: streamRawCodec{}}.Encode(&buf, NewLinkedSet(``)) != nil)
+		requireEqual(true, StreamFormat{Codec: streamRawCodec{}}.Encode(&buf, NewLinkedSet("one\r")) != nil)
+
+		buf.Reset()
+		try(StreamFormat{Codec: streamRawCodec{}, Binary: true}.Encode(&buf, set))
+		out := new(LinkedSet)
+		try(StreamFormat{Codec: streamRawCodec{}, Binary: true}.Decode(&buf, out))
+		requireEqual(set.Values(), out.Values())
+	})
+
+	t.Run("line limit", func(t *T) {
+		line, err := readLine(bufio.NewReaderSize(strings.NewReader("one\ntwo"), 16), 32)
+		try(err)
+		requireEqual("one\n", string(line))
+
+		line, err = readLine(bufio.NewReaderSize(strings.NewReader(strings.Repeat(`x`, 100)), 16), 32)
+		try(err)
+		requireEqual(48, len(line))
+	})
+
+	t.Run("Strict", func(t *T) {
+		src := "\"one\"\n\"two\"\n\"one\"\n\"three\"\n"
+
+		out := new(LinkedSet)
+		try(Decode(strings.NewReader(src), out))
+		requireEqual([]interface{}{`one`, `two`, `three`}, out.Values())
+
+		out = new(LinkedSet)
+		err := StreamFormat{Strict: true}.Decode(strings.NewReader(src), out)
+		requireEqual(true, errors.Is(err, ErrDuplicate))
+		requireEqual(`[gord] duplicate value "one" at index 2`, err.Error())
+		requireEqual([]interface{}{`one`, `two`}, out.Values())
+	})
+
+	t.Run("decode error", func(t *T) {
+		err := Decode(strings.NewReader("\"one\"\n{\n"), new(LinkedSet))
+		requireEqual(true, err != nil && strings.Contains(err.Error(), `at index 1`))
+	})
+}
+
+// Encodes strings as-is.
+type streamRawCodec struct{}
+
+func (streamRawCodec) Encode(val interface{}) ([]byte, error) { return []byte(val.(string)), nil }
+func (streamRawCodec) Decode(src []byte) (interface{}, error) { return string(src), nil }
+
+type eachSet interface {
+	OrdSet
+	Each(func(int, interface{}))
+	EachErr(func(int, interface{}) error) error
+	EachCtx(context.Context, func(int, interface{}) error) error
+}
+
+func TestEach(t *T) {
+	sets := []func(...interface{}) eachSet{
+		func(vals ...interface{}) eachSet { return NewLinkedSet(vals...) },
+		func(vals ...interface{}) eachSet { return NewSyncLinkedSet(vals...) },
+		func(vals ...interface{}) eachSet { return NewSliceSet(vals...) },
+		func(vals ...interface{}) eachSet { return NewSyncSliceSet(vals...) },
+		func(vals ...interface{}) eachSet { return NewCowSliceSet(vals...) },
+	}
+
+	for _, newSet := range sets {
+		set := newSet(10, 20, 30)
+
+		var got []pair
+		set.Each(func(i int, val interface{}) { got = append(got, pair{val, i > 0}) })
+		requireEqual([]pair{{10, false}, {20, true}, {30, true}}, got)
+
+		var indexes []int
+		fail := fmt.Errorf(`fail`)
+		err := set.EachErr(func(i int, val interface{}) error {
+			indexes = append(indexes, i)
+			if val == 20 {
+				return fail
+			}
+			return nil
+		})
+		requireEqual(fail, err)
+		requireEqual([]int{0, 1}, indexes)
+
+		requireEqual(nil, set.EachErr(func(int, interface{}) error { return nil }))
+		requireEqual(nil, newSet().EachErr(func(int, interface{}) error { return fail }))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		indexes = nil
+		err = set.EachCtx(ctx, func(i int, _ interface{}) error {
+			indexes = append(indexes, i)
+			if i == 1 {
+				cancel()
+			}
+			return nil
+		})
+		requireEqual(context.Canceled, err)
+		requireEqual([]int{0, 1}, indexes)
+	}
+
+	var linked *LinkedSet
+	linked.Each(func(int, interface{}) { panic(`unreachable`) })
+	requireEqual(nil, linked.EachErr(nil))
+
+	t.Run("Snapshot", func(t *T) {
+		for _, set := range []interface {
+			OrdSet
+			Snapshot() SliceSet
+		}{NewSyncLinkedSet(10, 20, 30), NewSyncSliceSet(10, 20, 30)} {
+			snap := set.Snapshot()
+
+			// Holding the lock here would deadlock.
+			try(snap.EachCtx(context.Background(), func(_ int, val interface{}) error {
+				set.Delete(val)
+				return nil
+			}))
+
+			requireEqual(0, set.Len())
+			requireEqual(SliceSet{10, 20, 30}, snap)
+		}
+	})
+}
+
+func TestVersion(t *T) {
+	var set LinkedSet
+	requireEqual(uint64(0), set.Version())
+
+	// Each step must change the version if, and only if, it modifies the set.
+	steps := []struct {
+		fun     func()
+		changed bool
+	}{
+		{func() { set.Add(10) }, true},
+		{func() { set.Add(20) }, true},
+		{func() { set.Add(30) }, true},
+		{func() { set.Add(10) }, false},
+		{func() { set.Delete(40) }, false},
+		{func() { set.AddFirst(30) }, true},
+		{func() { set.AddLast(30) }, true},
+		{func() { set.MoveFirst(40) }, false},
+		{func() { set.MoveFirst(20) }, true},
+		{func() { set.MoveLast(20) }, true},
+		{func() { set.Swap(10, 20) }, true},
+		{func() { set.Swap(10, 10) }, false},
+		{func() { set.MoveTo(10, 0) }, true},
+		{func() { set.Rotate(3) }, false},
+		{func() { set.Rotate(1) }, true},
+		{func() { set.Replace(10, 40) }, true},
+		{func() { set.Replace(10, 50) }, false},
+		{func() { set.PoppedFirst() }, true},
+		{func() { try(Apply(&set, []Edit{{Op: EditMove, Val: 30, First: true}})) }, true},
+		{func() { cur, _ := set.CursorAt(40); cur.MoveToBack() }, true},
+		{func() { set.Drain() }, true},
+		{func() { set.Drain() }, false},
+	}
+
+	for i, step := range steps {
+		prev := set.Version()
+		step.fun()
+		if (set.Version() != prev) != step.changed {
+			panic(fmt.Errorf(`step %v: version changed from %v to %v, expected change: %v`, i, prev, set.Version(), step.changed))
+		}
+	}
+
+	var nilSet *LinkedSet
+	requireEqual(uint64(0), nilSet.Version())
+	requireEqual(uint64(2), NewSyncLinkedSet(10, 20, 10).Version())
+
+	for _, set := range []versionSliceSet{new(SyncSliceSet), new(CowSliceSet)} {
+		steps := []struct {
+			fun     func()
+			changed bool
+		}{
+			{func() { set.Add(10) }, true},
+			{func() { set.Add(20) }, true},
+			{func() { set.Add(30) }, true},
+			{func() { set.Add(10) }, false},
+			{func() { set.Delete(40) }, false},
+			{func() { set.AddFirst(30) }, true},
+			{func() { set.AddLast(30) }, true},
+			{func() { set.AddLast(30) }, false},
+			{func() { set.AddedFirst(10) }, false},
+			{func() { set.Swap(10, 20) }, true},
+			{func() { set.Swap(10, 10) }, false},
+			{func() { set.Swap(10, 40) }, false},
+			{func() { set.MoveTo(10, 2) }, true},
+			{func() { set.MoveTo(40, 0) }, false},
+			{func() { set.Rotate(3) }, false},
+			{func() { set.Rotate(1) }, true},
+			{func() { try(set.Set(`10`)) }, true},
+			{func() { try(set.Set(`10`)) }, false},
+			{func() { set.Update(func(set *SliceSet) { set.Delete(`10`); set.Add(`20`) }) }, true},
+			{func() { try(set.Scan(`{10,20}`)) }, true},
+			{func() { set.PoppedFirst() }, true},
+			{func() { set.Drain() }, true},
+			{func() { set.Drain() }, false},
+			{func() { set.PoppedFirst() }, false},
+		}
+
+		for i, step := range steps {
+			prev := set.Version()
+			step.fun()
+			if (set.Version() != prev) != step.changed {
+				panic(fmt.Errorf(`%T step %v: version changed from %v to %v, expected change: %v`, set, i, prev, set.Version(), step.changed))
+			}
+		}
+	}
+
+	requireEqual(uint64(0), (*SyncSliceSet)(nil).Version())
+	requireEqual(uint64(0), (*CowSliceSet)(nil).Version())
+}
+
+type versionSliceSet interface {
+	OrdSet
+	Delete(interface{})
+	Swap(interface{}, interface{})
+	MoveTo(interface{}, int)
+	Rotate(int)
+	Drain() []interface{}
+	Update(func(*SliceSet))
+	Set(string) error
+	Scan(interface{}) error
+	Version() uint64
+}
+
+func TestConcurrentModification(t *T) {
+	modified := func(fun func()) (out *ConcurrentModificationError) {
+		defer func() { out = recover().(*ConcurrentModificationError) }()
+		fun()
+		return nil
+	}
+
+	t.Run("LinkedSet", func(t *T) {
+		set := NewLinkedSet(10, 20, 30)
+
+		err := modified(func() {
+			set.Each(func(_ int, val interface{}) {
+				if val == 20 {
+					set.MoveFirst(30)
+				}
+			})
+		})
+		requireEqual(&ConcurrentModificationError{Index: 1, Value: 20}, err)
+		requireEqual(`[gord] set modified during iteration by the callback for value 20 at index 1; iterate over a copy of the values instead`, err.Error())
+
+		err = modified(func() {
+			_ = set.EachErr(func(_ int, val interface{}) error {
+				set.Delete(val)
+				return nil
+			})
+		})
+		requireEqual(&ConcurrentModificationError{Index: 0, Value: 30}, err)
+
+		// No-op mutations are allowed.
+		set.Each(func(_ int, val interface{}) { set.Add(val) })
+		requireEqual([]interface{}{10, 20}, set.Values())
+	})
+
+	t.Run("SliceSet", func(t *T) {
+		set := NewSliceSet(10, 20, 30)
+
+		err := modified(func() {
+			set.Each(func(_ int, val interface{}) {
+				if val == 20 {
+					set.Add(40)
+				}
+			})
+		})
+		requireEqual(&ConcurrentModificationError{Index: 1, Value: 20}, err)
+
+		err = modified(func() {
+			_ = set.EachCtx(context.Background(), func(_ int, val interface{}) error {
+				set.Delete(val)
+				return nil
+			})
+		})
+		requireEqual(&ConcurrentModificationError{Index: 0, Value: 10}, err)
+
+		// Reordering in place is the documented blind spot.
+		set.Each(func(int, interface{}) { set.Rotate(1) })
+		requireEqual(SliceSet{20, 30, 40}, *set)
+	})
+}
+
+func TestCursor(t *T) {
+	t.Run("walk", func(t *T) {
+		set := NewLinkedSet(counterVals(10)...)
+		var visited []interface{}
+
+		for cur := set.Cursor(); cur.Next(); {
+			val, _ := cur.Value()
+			visited = append(visited, val)
+			if val.(int)%2 == 0 {
+				requireEqual(true, cur.Remove())
+				requireEqual(false, cur.Remove())
+				requireEqual(pair{nil, false}, toPair(cur.Value()))
+			}
+		}
+
+		requireEqual(counterVals(10), visited)
+		requireEqual([]interface{}{1, 3, 5, 7, 9}, set.Values())
+		requireEqual(nil, set.Validate())
+
+		visited = nil
+		for cur := set.CursorEnd(); cur.Prev(); {
+			val, _ := cur.Value()
+			visited = append(visited, val)
+			cur.Remove()
+		}
+		requireEqual([]interface{}{9, 7, 5, 3, 1}, visited)
+		requireEqual(0, set.Len())
+		requireEqual(false, set.Cursor().Next())
+		requireEqual(false, set.CursorEnd().Prev())
+	})
+
+	t.Run("gap", func(t *T) {
+		set := NewLinkedSet(1, 2, 3, 4, 5)
+		cur, _ := set.CursorAt(3)
+		cur.Remove()
+		requireEqual(true, cur.Prev())
+		requireEqual(pair{2, true}, toPair(cur.Value()))
+
+		cur, _ = set.CursorAt(4)
+		cur.Remove()
+		set.Delete(5)
+		requireEqual(true, cur.Prev())
+		requireEqual(pair{2, true}, toPair(cur.Value()))
+
+		// The gap was at the end, which survives removing the other border.
+		cur.Remove()
+		set.Delete(1)
+		requireEqual(false, cur.Next())
+		requireEqual(false, cur.Prev())
+		requireEqual(true, cur.InsertAfter(10))
+		requireEqual([]interface{}{10}, set.Values())
+
+		set = NewLinkedSet(1, 2, 3)
+		cur, _ = set.CursorAt(2)
+		cur.Remove()
+		set.Delete(1)
+		set.Delete(3)
+		requireEqual(false, cur.Next())
+		requireEqual(false, cur.Prev())
+		requireEqual(false, cur.InsertAfter(10))
+		requireEqual([]interface{}{}, set.Values())
+
+		set = NewLinkedSet(1, 2, 3)
+		cur, _ = set.CursorAt(1)
+		cur.Remove()
+		set.Delete(2)
+		requireEqual(true, cur.Next())
+		requireEqual(pair{3, true}, toPair(cur.Value()))
+		requireEqual(false, cur.Next())
+		requireEqual(true, cur.Prev())
+		requireEqual(pair{3, true}, toPair(cur.Value()))
+	})
+
+	t.Run("removed elsewhere", func(t *T) {
+		set := NewLinkedSet(1, 2, 3)
+		cur, _ := set.CursorAt(2)
+		set.Delete(2)
+		requireEqual(pair{nil, false}, toPair(cur.Value()))
+		requireEqual(false, cur.Next())
+		requireEqual(false, cur.Prev())
+		requireEqual(false, cur.Remove())
+
+		set.Add(2)
+		requireEqual(false, cur.Next())
+	})
+
+	t.Run("moved elsewhere", func(t *T) {
+		set := NewLinkedSet(1, 2, 3)
+		cur, _ := set.CursorAt(2)
+		set.MoveLast(2)
+		requireEqual(false, cur.Next())
+		requireEqual(true, cur.Prev())
+		requireEqual(true, cur.Prev())
+		requireEqual(pair{3, true}, toPair(cur.Value()))
+	})
+
+	t.Run("added elsewhere", func(t *T) {
+		set := NewLinkedSet(1)
+		cur := set.Cursor()
+		requireEqual(true, cur.Next())
+		requireEqual(false, cur.Next())
+		set.Add(2)
+		requireEqual(true, cur.Next())
+		requireEqual(pair{2, true}, toPair(cur.Value()))
+	})
+
+	t.Run("MoveToFront", func(t *T) {
+		set := NewLinkedSet(1, 2, 3, 4)
+		var visited []interface{}
+		for cur := set.Cursor(); cur.Next(); {
+			val, _ := cur.Value()
+			visited = append(visited, val)
+			if val.(int) > 2 {
+				requireEqual(true, cur.MoveToFront())
+			}
+		}
+		requireEqual([]interface{}{1, 2, 3, 4}, visited)
+		requireEqual([]interface{}{4, 3, 1, 2}, set.Values())
+		requireEqual(nil, set.Validate())
+	})
+
+	t.Run("MoveToBack", func(t *T) {
+		set := NewLinkedSet(1, 2, 3, 4)
+		cur := set.CursorEnd()
+		for cur.Prev() {
+			val, _ := cur.Value()
+			if val.(int) < 3 {
+				requireEqual(true, cur.MoveToBack())
+			}
+		}
+		requireEqual([]interface{}{3, 4, 2, 1}, set.Values())
+	})
+
+	t.Run("Insert", func(t *T) {
+		set := NewLinkedSet(1, 2, 3)
+		cur, _ := set.CursorAt(2)
+		requireEqual(false, cur.InsertAfter(3))
+		requireEqual(true, cur.InsertAfter(10))
+		requireEqual(true, cur.InsertBefore(20))
+		requireEqual([]interface{}{1, 20, 2, 10, 3}, set.Values())
+
+		cur.Remove()
+		requireEqual(true, cur.InsertBefore(30))
+		requireEqual(true, cur.InsertAfter(40))
+		requireEqual([]interface{}{1, 20, 30, 40, 10, 3}, set.Values())
+		requireEqual(true, cur.Next())
+		requireEqual(pair{40, true}, toPair(cur.Value()))
+		requireEqual(nil, set.Validate())
+
+		set = new(LinkedSet)
+		cur = set.Cursor()
+		requireEqual(true, cur.InsertBefore(1))
+		requireEqual(true, cur.InsertAfter(2))
+		requireEqual([]interface{}{1, 2}, set.Values())
+
+		cur = set.CursorEnd()
+		requireEqual(true, cur.InsertAfter(3))
+		requireEqual(true, cur.InsertBefore(4))
+		requireEqual([]interface{}{1, 2, 4, 3}, set.Values())
+		requireEqual(true, cur.Prev())
+		requireEqual(pair{4, true}, toPair(cur.Value()))
+		requireEqual(nil, set.Validate())
+	})
+
+	t.Run("nil", func(t *T) {
+		var set *LinkedSet
+		cur := set.Cursor()
+		requireEqual(false, cur.Next())
+		requireEqual(false, cur.InsertAfter(1))
+		_, ok := set.CursorAt(1)
+		requireEqual(false, ok)
+	})
+}
+
+func counterVals(count int) []interface{} {
+	out := make([]interface{}, count)
+	for i := range out {
+		out[i] = i
+	}
+	return out
+}
+
+type fakeClock struct {
+	lock sync.Mutex
+	now  time.Time
+}
+
+func (self *fakeClock) Now() time.Time {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.now
+}
+
+func (self *fakeClock) advance(dur time.Duration) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.now = self.now.Add(dur)
+}
+
+// Batch methods shared by every type.
+type batchSet interface {
+	OrdSet
+	PoppedFirstN(int) []interface{}
+	PoppedLastN(int) []interface{}
+	Drain() []interface{}
+}
+
+// Reordering methods shared by every type.
+type reorderSet interface {
+	NavOrdSet
+	Swapped(interface{}, interface{}) bool
+	MovedTo(interface{}, int) bool
+	Rotate(int)
+}
+
+func testReorderSet(t *T, newSet func() reorderSet) {
+	t.Run("Swapped", func(t *T) { testSetSwapped(newSet()) })
+	t.Run("MovedTo", func(t *T) { testSetMovedTo(newSet()) })
+	t.Run("Rotate", func(t *T) { testSetRotate(newSet()) })
+}
+
+func testBatchSet(t *T, newSet func() batchSet) {
+	t.Run("PoppedFirstN", func(t *T) { testSetPoppedFirstN(newSet()) })
+	t.Run("PoppedLastN", func(t *T) { testSetPoppedLastN(newSet()) })
+	t.Run("Drain", func(t *T) { testSetDrain(newSet()) })
+}
+
+func testSetPoppedFirstN(set batchSet) {
+	requireEqual(0, len(set.PoppedFirstN(3)))
+
+	for _, val := range []interface{}{20, 10, 30, 40, 50} {
+		set.Add(val)
+	}
+
+	requireEqual(0, len(set.PoppedFirstN(0)))
+	requireEqual(0, len(set.PoppedFirstN(-1)))
+	requireEqual([]interface{}{20, 10, 30, 40, 50}, set.Values())
+
+	requireEqual([]interface{}{20, 10}, set.PoppedFirstN(2))
+	requireEqual([]interface{}{30, 40, 50}, set.Values())
+	requireEqual(false, set.Has(20))
+
+	requireEqual([]interface{}{30, 40, 50}, set.PoppedFirstN(10))
+	requireEqual(0, set.Len())
+
+	set.Add(20)
+	requireEqual([]interface{}{20}, set.Values())
+}
+
+func testSetPoppedLastN(set batchSet) {
+	requireEqual(0, len(set.PoppedLastN(3)))
+
+	for _, val := range []interface{}{20, 10, 30, 40, 50} {
+		set.Add(val)
+	}
+
+	requireEqual(0, len(set.PoppedLastN(0)))
+	requireEqual([]interface{}{20, 10, 30, 40, 50}, set.Values())
+
+	requireEqual([]interface{}{40, 50}, set.PoppedLastN(2))
+	requireEqual([]interface{}{20, 10, 30}, set.Values())
+	requireEqual(false, set.Has(50))
+
+	requireEqual([]interface{}{20, 10, 30}, set.PoppedLastN(10))
+	requireEqual(0, set.Len())
+
+	set.Add(50)
+	requireEqual([]interface{}{50}, set.Values())
+}
+
+func testSetDrain(set batchSet) {
+	requireEqual(0, len(set.Drain()))
 
-	t.Run("GoString", func(t *T) {
-		requireEqual(`(*SliceSet)(nil)`, (*SliceSet)(nil).GoString())
+	set.Add(20)
+	set.Add(10)
+	set.Add(30)
 
-		var set SliceSet
-		requireEqual(`SliceSet(nil)`, set.GoString())
+	requireEqual([]interface{}{20, 10, 30}, set.Drain())
+	requireEqual(0, set.Len())
+	requireEqual(false, set.Has(20))
 
-		set.Add(20)
-		requireEqual(`SliceSet{20}`, set.GoString())
+	set.Add(10)
+	requireEqual([]interface{}{10}, set.Values())
+}
 
-		set.Add(10)
-		set.Add(30)
-		requireEqual(`SliceSet{20, 10, 30}`, set.GoString())
-	})
+// Extra methods shared by `LinkedSet` and `SyncLinkedSet`.
+type compoundSet interface {
+	OrdSet
+	MovedFirst(interface{}) bool
+	MovedLast(interface{}) bool
+	Replaced(interface{}, interface{}) bool
 }
 
-func testSet(t *T, newSet func() OrdSet) {
-	t.Run("Len", func(t *T) { testSetLen(newSet()) })
-	t.Run("Has", func(t *T) { testSetHas(newSet()) })
-	t.Run("Add", func(t *T) { testSetAdd(newSet()) })
-	t.Run("Added", func(t *T) { testSetAdded(newSet()) })
-	t.Run("Delete", func(t *T) { testSetDelete(newSet()) })
-	t.Run("Deleted", func(t *T) { testSetDeleted(newSet()) })
-	t.Run("AddFirst", func(t *T) { testSetAddFirst(newSet()) })
-	t.Run("AddedFirst", func(t *T) { testSetAddedFirst(newSet()) })
-	t.Run("AddLast", func(t *T) { testSetAddLast(newSet()) })
-	t.Run("AddedLast", func(t *T) { testSetAddedLast(newSet()) })
-	t.Run("PoppedFirst", func(t *T) { testSetPoppedFirst(newSet()) })
-	t.Run("PoppedLast", func(t *T) { testSetPoppedLast(newSet()) })
-	t.Run("Values", func(t *T) { testSetValues(newSet()) })
+func testCompoundSet(t *T, newSet func() compoundSet) {
+	t.Run("MovedFirst", func(t *T) { testSetMovedFirst(newSet()) })
+	t.Run("MovedLast", func(t *T) { testSetMovedLast(newSet()) })
+	t.Run("Replaced", func(t *T) { testSetReplaced(newSet()) })
 }
 
-// Relies on correctness of `Add`, which is tested later.
-func testSetLen(set OrdSet) {
+func testSetMovedFirst(set compoundSet) {
+	requireEqual(false, set.MovedFirst(20))
 	requireEqual(0, set.Len())
 
 	set.Add(20)
-	requireEqual(1, set.Len())
-	requireEqual([]interface{}{20}, set.Values())
+	set.Add(10)
+	set.Add(30)
 
-	set.Add(20)
-	requireEqual(1, set.Len())
-	requireEqual([]interface{}{20}, set.Values())
+	requireEqual(true, set.MovedFirst(30))
+	requireEqual([]interface{}{30, 20, 10}, set.Values())
 
-	set.Add(10)
-	requireEqual(2, set.Len())
-	requireEqual([]interface{}{20, 10}, set.Values())
+	requireEqual(true, set.MovedFirst(30))
+	requireEqual([]interface{}{30, 20, 10}, set.Values())
 
-	set.Add(10)
-	requireEqual(2, set.Len())
-	requireEqual([]interface{}{20, 10}, set.Values())
+	requireEqual(false, set.MovedFirst(40))
+	requireEqual([]interface{}{30, 20, 10}, set.Values())
+}
 
-	set.Add(30)
-	requireEqual(3, set.Len())
-	requireEqual([]interface{}{20, 10, 30}, set.Values())
+func testSetMovedLast(set compoundSet) {
+	requireEqual(false, set.MovedLast(20))
+	requireEqual(0, set.Len())
 
+	set.Add(20)
+	set.Add(10)
 	set.Add(30)
-	requireEqual(3, set.Len())
-	requireEqual([]interface{}{20, 10, 30}, set.Values())
+
+	requireEqual(true, set.MovedLast(20))
+	requireEqual([]interface{}{10, 30, 20}, set.Values())
+
+	requireEqual(true, set.MovedLast(20))
+	requireEqual([]interface{}{10, 30, 20}, set.Values())
+
+	requireEqual(false, set.MovedLast(40))
+	requireEqual([]interface{}{10, 30, 20}, set.Values())
 }
 
-func testSetHas(set OrdSet) {
+func testSetReplaced(set compoundSet) {
+	requireEqual(false, set.Replaced(20, 40))
 	requireEqual(0, set.Len())
 
 	set.Add(20)
-	requireEqual(true, set.Has(20))
-	requireEqual(false, set.Has(10))
-	requireEqual(false, set.Has(30))
+	set.Add(10)
+	set.Add(30)
 
-	set.Add(20)
-	requireEqual(true, set.Has(20))
+	requireEqual(true, set.Replaced(10, 40))
+	requireEqual([]interface{}{20, 40, 30}, set.Values())
 	requireEqual(false, set.Has(10))
-	requireEqual(false, set.Has(30))
+	requireEqual(true, set.Has(40))
 
-	set.Add(10)
-	requireEqual(true, set.Has(20))
-	requireEqual(true, set.Has(10))
-	requireEqual(false, set.Has(30))
+	requireEqual(true, set.Replaced(40, 40))
+	requireEqual([]interface{}{20, 40, 30}, set.Values())
 
-	set.Add(10)
-	requireEqual(true, set.Has(20))
-	requireEqual(true, set.Has(10))
-	requireEqual(false, set.Has(30))
+	requireEqual(true, set.Replaced(20, 30))
+	requireEqual([]interface{}{30, 40}, set.Values())
+	requireEqual(2, set.Len())
 
+	requireEqual(false, set.Replaced(10, 50))
+	requireEqual([]interface{}{30, 40}, set.Values())
+}
+
+func testSetSwapped(set reorderSet) {
+	requireEqual(false, set.Swapped(20, 10))
+
+	set.Add(20)
+	set.Add(10)
 	set.Add(30)
-	requireEqual(true, set.Has(20))
-	requireEqual(true, set.Has(10))
-	requireEqual(true, set.Has(30))
+	set.Add(40)
+
+	requireEqual(true, set.Swapped(20, 10))
+	requireEqual([]interface{}{10, 20, 30, 40}, set.Values())
+
+	requireEqual(true, set.Swapped(30, 20))
+	requireEqual([]interface{}{10, 30, 20, 40}, set.Values())
+
+	requireEqual(true, set.Swapped(10, 40))
+	requireEqual([]interface{}{40, 30, 20, 10}, set.Values())
+
+	requireEqual(true, set.Swapped(30, 30))
+	requireEqual([]interface{}{40, 30, 20, 10}, set.Values())
+
+	requireEqual(false, set.Swapped(30, 50))
+	requireEqual([]interface{}{40, 30, 20, 10}, set.Values())
+
+	for _, val := range set.Values() {
+		requireEqual(true, set.Has(val))
+	}
+}
+
+func testSetMovedTo(set reorderSet) {
+	requireEqual(false, set.MovedTo(10, 0))
 
+	set.Add(10)
+	set.Add(20)
 	set.Add(30)
-	requireEqual(true, set.Has(20))
-	requireEqual(true, set.Has(10))
-	requireEqual(true, set.Has(30))
+	set.Add(40)
+	set.Add(50)
+
+	requireEqual(true, set.MovedTo(10, 2))
+	requireEqual([]interface{}{20, 30, 10, 40, 50}, set.Values())
+
+	requireEqual(true, set.MovedTo(50, 1))
+	requireEqual([]interface{}{20, 50, 30, 10, 40}, set.Values())
+
+	requireEqual(true, set.MovedTo(20, 4))
+	requireEqual([]interface{}{50, 30, 10, 40, 20}, set.Values())
+
+	requireEqual(true, set.MovedTo(40, 0))
+	requireEqual([]interface{}{40, 50, 30, 10, 20}, set.Values())
+
+	requireEqual(true, set.MovedTo(30, 3))
+	requireEqual([]interface{}{40, 50, 10, 30, 20}, set.Values())
+
+	requireEqual(true, set.MovedTo(10, 2))
+	requireEqual([]interface{}{40, 50, 10, 30, 20}, set.Values())
+
+	requireEqual(false, set.MovedTo(60, 0))
+	requireEqual(false, set.MovedTo(10, -1))
+	requireEqual(false, set.MovedTo(10, 5))
+	requireEqual([]interface{}{40, 50, 10, 30, 20}, set.Values())
+
+	// Moving by an equal value keeps the stored one: `0.0` rather than `-0.0`.
+	negZero := math.Copysign(0, -1)
+	set.Add(0.0)
+	requireEqual(true, set.MovedTo(negZero, 0))
+	first, _ := set.First()
+	requireEqual(false, math.Signbit(first.(float64)))
+	requireEqual(true, set.MovedTo(negZero, set.Len()-1))
+	last, _ := set.Last()
+	requireEqual(false, math.Signbit(last.(float64)))
+	set.Delete(0.0)
+
+	// Compare every combination against a plain slice.
+	for from := range counter(5) {
+		for to := range counter(5) {
+			vals := []interface{}{0, 1, 2, 3, 4}
+			expected := append(append([]interface{}{}, vals[:from]...), vals[from+1:]...)
+			expected = append(expected[:to], append([]interface{}{from}, expected[to:]...)...)
+
+			for _, val := range append([]interface{}(nil), set.Values()...) {
+				set.Delete(val)
+			}
+			for _, val := range vals {
+				set.Add(val)
+			}
+
+			requireEqual(true, set.MovedTo(from, to))
+			requireEqual(expected, set.Values())
+			requireEqual(true, set.Has(from))
+		}
+	}
 }
 
-func testSetAdd(set OrdSet) {
+func testSetRotate(set reorderSet) {
+	set.Rotate(1)
 	requireEqual(0, set.Len())
 
-	set.Add(20)
-	requireEqual([]interface{}{20}, set.Values())
+	set.Add(10)
+	set.Rotate(-3)
+	requireEqual([]interface{}{10}, set.Values())
 
 	set.Add(20)
-	requireEqual([]interface{}{20}, set.Values())
+	set.Add(30)
+	set.Add(40)
+	set.Add(50)
 
-	set.Add(10)
-	requireEqual([]interface{}{20, 10}, set.Values())
+	set.Rotate(1)
+	requireEqual([]interface{}{20, 30, 40, 50, 10}, set.Values())
 
-	set.Add(10)
-	requireEqual([]interface{}{20, 10}, set.Values())
+	set.Rotate(-1)
+	requireEqual([]interface{}{10, 20, 30, 40, 50}, set.Values())
 
-	set.Add(30)
-	requireEqual([]interface{}{20, 10, 30}, set.Values())
+	set.Rotate(4)
+	requireEqual([]interface{}{50, 10, 20, 30, 40}, set.Values())
 
-	set.Add(30)
-	requireEqual([]interface{}{20, 10, 30}, set.Values())
-}
+	set.Rotate(-2)
+	requireEqual([]interface{}{30, 40, 50, 10, 20}, set.Values())
 
-func testSetAdded(set OrdSet) {
-	requireEqual(0, set.Len())
+	set.Rotate(5)
+	requireEqual([]interface{}{30, 40, 50, 10, 20}, set.Values())
 
-	requireEqual(true, set.Added(20))
-	requireEqual([]interface{}{20}, set.Values())
+	set.Rotate(12)
+	requireEqual([]interface{}{50, 10, 20, 30, 40}, set.Values())
 
-	requireEqual(false, set.Added(20))
-	requireEqual([]interface{}{20}, set.Values())
+	set.Rotate(-7)
+	requireEqual([]interface{}{30, 40, 50, 10, 20}, set.Values())
 
-	requireEqual(true, set.Added(10))
-	requireEqual([]interface{}{20, 10}, set.Values())
+	for _, val := range set.Values() {
+		requireEqual(true, set.Has(val))
+	}
+}
 
-	requireEqual(false, set.Added(10))
-	requireEqual([]interface{}{20, 10}, set.Values())
+func testSetNew(new func(...interface{}) OrdSet) {
+	requireEqual(0, new().Len())
+	requireEqual([]interface{}{20}, new(20).Values())
+	requireEqual([]interface{}{20, 10}, new(20, 10).Values())
+	requireEqual([]interface{}{20, 10, 30}, new(20, 10, 30).Values())
+	requireEqual([]interface{}{20, 10, 30}, new(20, 10, 30, 20, 30, 10).Values())
+}
 
-	requireEqual(true, set.Added(30))
-	requireEqual([]interface{}{20, 10, 30}, set.Values())
+// Tests `.Slice`, `.Between`, `.Take`, `.TakeLast` and `.Drop`, which every
+// type declares with its own return type, hence the reflection.
+func testSetRange(new func(...interface{}) OrdSet) {
+	set := new(20, 10, 30, 40, 50)
+	values := func(name string, args ...interface{}) []interface{} {
+		return callSet(set, name, args...).(OrdSet).Values()
+	}
 
-	requireEqual(false, set.Added(30))
-	requireEqual([]interface{}{20, 10, 30}, set.Values())
+	requireEqual([]interface{}{10, 30}, values(`Slice`, 1, 3))
+	requireEqual([]interface{}{20, 10, 30, 40, 50}, values(`Slice`, 0, 5))
+	requireEqual([]interface{}{40, 50}, values(`Slice`, 3, 10))
+	requireEqual([]interface{}{20, 10}, values(`Slice`, -5, 2))
+	requireEqual(0, len(values(`Slice`, 3, 3)))
+	requireEqual(0, len(values(`Slice`, 4, 2)))
+	requireEqual(0, len(values(`Slice`, 7, 9)))
+	requireEqual(0, len(values(`Slice`, -3, -1)))
+
+	requireEqual([]interface{}{10, 30, 40}, values(`Between`, 10, 40))
+	requireEqual([]interface{}{30}, values(`Between`, 30, 30))
+	requireEqual([]interface{}{20, 10, 30, 40, 50}, values(`Between`, 20, 50))
+	requireEqual(0, len(values(`Between`, 40, 10)))
+	requireEqual(0, len(values(`Between`, 10, 60)))
+	requireEqual(0, len(values(`Between`, 60, 10)))
+
+	requireEqual([]interface{}{20, 10}, values(`Take`, 2))
+	requireEqual([]interface{}{20, 10, 30, 40, 50}, values(`Take`, 10))
+	requireEqual(0, len(values(`Take`, 0)))
+
+	requireEqual([]interface{}{40, 50}, values(`TakeLast`, 2))
+	requireEqual([]interface{}{20, 10, 30, 40, 50}, values(`TakeLast`, 10))
+	requireEqual(0, len(values(`TakeLast`, 0)))
+	requireEqual(0, len(values(`TakeLast`, -1)))
+
+	requireEqual([]interface{}{30, 40, 50}, values(`Drop`, 2))
+	requireEqual([]interface{}{20, 10, 30, 40, 50}, values(`Drop`, -1))
+	requireEqual(0, len(values(`Drop`, 5)))
+
+	// The results must be independent from the original.
+	part := callSet(set, `Take`, 2).(OrdSet)
+	part.Add(60)
+	part.Delete(20)
+	requireEqual([]interface{}{20, 10, 30, 40, 50}, set.Values())
+
+	empty := new()
+	requireEqual(0, callSet(empty, `Slice`, 0, 1).(OrdSet).Len())
+	requireEqual(0, callSet(empty, `Between`, 10, 20).(OrdSet).Len())
+	requireEqual(0, callSet(empty, `TakeLast`, 3).(OrdSet).Len())
 }
 
-func testSetDelete(set OrdSet) {
-	requireEqual(0, set.Len())
+func testSetString(set StringerOrdSet) {
+	requireEqual(`[]`, set.String())
 
 	set.Add(20)
+	requireEqual(`[20]`, set.String())
+
 	set.Add(10)
 	set.Add(30)
-	requireEqual([]interface{}{20, 10, 30}, set.Values())
+	requireEqual(`[20 10 30]`, set.String())
+}
 
-	set.Delete(10)
-	requireEqual([]interface{}{20, 30}, set.Values())
+// Applies to both `LinkedSet` and `SyncLinkedSet`.
+func testLinkedSetGoString(set StringerOrdSet, constr string) {
+	requireEqual(constr+`()`, set.GoString())
 
-	set.Delete(10)
-	requireEqual([]interface{}{20, 30}, set.Values())
+	set.Add(20)
+	requireEqual(constr+`(20)`, set.GoString())
 
-	set.Delete(20)
-	requireEqual([]interface{}{30}, set.Values())
+	set.Add(10)
+	set.Add(30)
+	requireEqual(constr+`(20, 10, 30)`, set.GoString())
+}
 
-	set.Delete(20)
-	requireEqual([]interface{}{30}, set.Values())
+func TestFormat(t *T) {
+	test := func(set OrdSet, typ, goOpen, goClose string) {
+		requireEqual(`[]`, fmt.Sprintf(`%v`, set))
+		requireEqual(`[]`, fmt.Sprintf(`%.2v`, set))
+		requireEqual(typ+`(len=0)[]`, fmt.Sprintf(`%+v`, set))
+
+		for _, val := range []interface{}{20, 10, 30} {
+			set.Add(val)
+		}
+
+		requireEqual(`[20 10 30]`, fmt.Sprintf(`%v`, set))
+		requireEqual(fmt.Sprintf(`%s`, set.Values()), fmt.Sprintf(`%s`, set))
+		requireEqual(`[20 10 30]`, fmt.Sprintf(`%d`, set))
+		requireEqual(`[14 a 1e]`, fmt.Sprintf(`%x`, set))
+		requireEqual(`[  20   10   30]`, fmt.Sprintf(`%4d`, set))
+		requireEqual(`[20 10 30]`, fmt.Sprintf(`%.3v`, set))
+		requireEqual(`[20 10 30]`, fmt.Sprintf(`%.10v`, set))
+		requireEqual(`[20 10 …(+1 more)]`, fmt.Sprintf(`%.2v`, set))
+		requireEqual(`[…(+3 more)]`, fmt.Sprintf(`%.0v`, set))
+		requireEqual(typ+`(len=3)[20 …(+2 more)]`, fmt.Sprintf(`%+.1v`, set))
+		requireEqual(goOpen+`20, 10, 30`+goClose, fmt.Sprintf(`%#v`, set))
+		requireEqual(goOpen+`20, …(+2 more)`+goClose, fmt.Sprintf(`%#.1v`, set))
+
+		set.Add(`str`)
+		requireEqual(`[20 10 30 str]`, fmt.Sprint(set))
+		requireEqual(goOpen+`20, 10, 30, "str"`+goClose, fmt.Sprintf(`%#v`, set))
+	}
 
-	set.Delete(30)
-	requireEqual([]interface{}{}, set.Values())
+	test(new(LinkedSet), `*gord.LinkedSet`, `NewLinkedSet(`, `)`)
+	test(new(SyncLinkedSet), `*gord.SyncLinkedSet`, `NewSyncLinkedSet(`, `)`)
+	test(new(SliceSet), `*gord.SliceSet`, `SliceSet{`, `}`)
+	test(new(SyncSliceSet), `*gord.SyncSliceSet`, `NewSyncSliceSet(`, `)`)
+	test(new(CowSliceSet), `*gord.CowSliceSet`, `NewCowSliceSet(`, `)`)
 
-	set.Delete(30)
-	requireEqual([]interface{}{}, set.Values())
+	requireEqual(`[]`, fmt.Sprintf(`%v`, (*LinkedSet)(nil)))
+	requireEqual(`(*LinkedSet)(nil)`, fmt.Sprintf(`%#v`, (*LinkedSet)(nil)))
+	requireEqual(`SliceSet(nil)`, fmt.Sprintf(`%#v`, new(SliceSet)))
 }
 
-func testSetDeleted(set OrdSet) {
-	requireEqual(0, set.Len())
+// Formatting with a small precision must not depend on the size of the set.
+func BenchmarkLinkedSetFormatPrecision(b *B) {
+	set := new(LinkedSet)
+	for i := range counter(1 << 16) {
+		set.Add(i)
+	}
+	b.ResetTimer()
 
-	set.Add(20)
-	set.Add(10)
-	set.Add(30)
-	requireEqual([]interface{}{20, 10, 30}, set.Values())
+	for range counter(b.N) {
+		_ = fmt.Sprintf(`%.10v`, set)
+	}
+}
 
-	requireEqual(true, set.Deleted(10))
-	requireEqual([]interface{}{20, 30}, set.Values())
+func TestDiff(t *T) {
+	requireEqual([]Edit(nil), Diff(NewLinkedSet(), NewLinkedSet()))
+	requireEqual([]Edit(nil), Diff(NewLinkedSet(20, 10, 30), NewSliceSet(20, 10, 30)))
+
+	requireEqual(
+		[]Edit{
+			{Op: EditDelete, Val: 10},
+			{Op: EditInsert, Val: 40, First: true},
+			{Op: EditMove, Val: 50, After: 30},
+		},
+		Diff(NewLinkedSet(20, 10, 50, 30), NewLinkedSet(40, 20, 30, 50)),
+	)
+
+	// Moving one value across the set takes one edit.
+	requireEqual(
+		[]Edit{{Op: EditMove, Val: 10, After: 50}},
+		Diff(NewLinkedSet(10, 20, 30, 40, 50), NewLinkedSet(20, 30, 40, 50, 10)),
+	)
+
+	// Reversal keeps one value in place.
+	requireEqual(3, len(Diff(NewLinkedSet(10, 20, 30, 40), NewLinkedSet(40, 30, 20, 10))))
+}
 
-	requireEqual(false, set.Deleted(10))
-	requireEqual([]interface{}{20, 30}, set.Values())
+func TestApply(t *T) {
+	newSets := []func(...interface{}) OrdSet{
+		func(vals ...interface{}) OrdSet { return NewLinkedSet(vals...) },
+		func(vals ...interface{}) OrdSet { return NewSyncLinkedSet(vals...) },
+		func(vals ...interface{}) OrdSet { return NewSliceSet(vals...) },
+		func(vals ...interface{}) OrdSet { return NewSyncSliceSet(vals...) },
+		func(vals ...interface{}) OrdSet { return NewCowSliceSet(vals...) },
+		// Not a known type; exercises the generic path.
+		func(vals ...interface{}) OrdSet { return TextSet{OrdSet: NewSliceSet(vals...)} },
+	}
 
-	requireEqual(true, set.Deleted(20))
-	requireEqual([]interface{}{30}, set.Values())
+	for _, newSet := range newSets {
+		for range counter(64) {
+			prev := newSet(randomVals(rnd.Intn(12))...)
+			next := NewLinkedSet(randomVals(rnd.Intn(12))...)
+			edits := Diff(prev, next)
+
+			requireEqual(nil, Apply(prev, edits))
+			requireEqual(next.Values(), nonNil(prev.Values()))
+			requireEqual(0, len(Diff(prev, next)))
+		}
+
+		set := newSet(20, 10, 30)
+		for _, edit := range []Edit{
+			{Op: EditDelete, Val: 40},
+			{Op: EditInsert, Val: 10, First: true},
+			{Op: EditMove, Val: 40, First: true},
+			{Op: EditMove, Val: 10, After: 40},
+			{Op: EditMove, Val: 10, After: 10},
+			{Val: 10},
+		} {
+			requireEqual(true, Apply(set, []Edit{edit}) != nil)
+		}
+		requireEqual([]interface{}{20, 10, 30}, set.Values())
+	}
+}
 
-	requireEqual(false, set.Deleted(20))
-	requireEqual([]interface{}{30}, set.Values())
+// Diffing random permutations must never move values from the longest
+// increasing subsequence.
+func TestDiffMinimal(t *T) {
+	for range counter(64) {
+		size := rnd.Intn(32)
+		perm := rnd.Perm(size)
+
+		prev, next := new(LinkedSet), new(LinkedSet)
+		for i := range counter(size) {
+			prev.Add(i)
+			next.Add(perm[i])
+		}
+
+		// Positions in `next` in the order of `prev`, for a brute-force count.
+		positions := make([]int, size)
+		for i, val := range perm {
+			positions[val] = i
+		}
+
+		requireEqual(size-lisLen(positions), len(Diff(prev, next)))
+	}
+}
 
-	requireEqual(true, set.Deleted(30))
-	requireEqual([]interface{}{}, set.Values())
+// Quadratic reference implementation.
+func lisLen(vals []int) int {
+	lens := make([]int, len(vals))
+	out := 0
+	for i := range vals {
+		lens[i] = 1
+		for j := 0; j < i; j++ {
+			if vals[j] < vals[i] && lens[j]+1 > lens[i] {
+				lens[i] = lens[j] + 1
+			}
+		}
+		if lens[i] > out {
+			out = lens[i]
+		}
+	}
+	return out
+}
 
-	requireEqual(false, set.Deleted(30))
-	requireEqual([]interface{}{}, set.Values())
+func randomVals(count int) []interface{} {
+	out := make([]interface{}, count)
+	for i := range out {
+		out[i] = rnd.Intn(16)
+	}
+	return out
 }
 
-func testSetAddFirst(set OrdSet) {
-	requireEqual(0, set.Len())
+func nonNil(vals []interface{}) []interface{} {
+	if vals == nil {
+		return []interface{}{}
+	}
+	return vals
+}
 
-	set.AddFirst(20)
-	requireEqual([]interface{}{20}, set.Values())
+func TestFingerprint(t *T) {
+	type hashSet interface {
+		OrdSet
+		Fingerprint() uint64
+		OrdFingerprint() uint64
+	}
 
-	set.AddFirst(20)
-	requireEqual([]interface{}{20}, set.Values())
+	newSets := []func(...interface{}) hashSet{
+		func(vals ...interface{}) hashSet { return NewLinkedSet(vals...) },
+		func(vals ...interface{}) hashSet { return NewSyncLinkedSet(vals...) },
+		func(vals ...interface{}) hashSet { return NewSliceSet(vals...) },
+		func(vals ...interface{}) hashSet { return NewSyncSliceSet(vals...) },
+		func(vals ...interface{}) hashSet { return NewCowSliceSet(vals...) },
+	}
 
-	set.AddFirst(10)
-	requireEqual([]interface{}{10, 20}, set.Values())
+	ref := NewLinkedSet(20, `10`, 30.5, nil, true, int8(20), struct{}{})
+	reordered := NewLinkedSet(`10`, 20, nil, 30.5, struct{}{}, true, int8(20))
 
-	set.AddFirst(10)
-	requireEqual([]interface{}{10, 20}, set.Values())
+	requireEqual(true, ref.Fingerprint() == reordered.Fingerprint())
+	requireEqual(false, ref.OrdFingerprint() == reordered.OrdFingerprint())
+	requireEqual(false, NewLinkedSet(20).Fingerprint() == NewLinkedSet(int64(20)).Fingerprint())
+	requireEqual(false, NewLinkedSet(20).Fingerprint() == NewLinkedSet(`20`).Fingerprint())
+	requireEqual(false, NewLinkedSet(10, 20).OrdFingerprint() == NewLinkedSet(20, 10).OrdFingerprint())
+	requireEqual(false, NewLinkedSet().OrdFingerprint() == NewLinkedSet(nil).OrdFingerprint())
 
-	set.AddFirst(30)
-	requireEqual([]interface{}{30, 10, 20}, set.Values())
+	for _, newSet := range newSets {
+		set := newSet(ref.Values()...)
+		requireEqual(ref.Fingerprint(), set.Fingerprint())
+		requireEqual(ref.OrdFingerprint(), set.OrdFingerprint())
 
-	set.AddFirst(30)
-	requireEqual([]interface{}{30, 10, 20}, set.Values())
+		empty := newSet()
+		requireEqual(uint64(0), empty.Fingerprint())
+		requireEqual(NewLinkedSet().OrdFingerprint(), empty.OrdFingerprint())
+	}
 
-	set.AddFirst(10)
-	requireEqual([]interface{}{10, 30, 20}, set.Values())
+	// The running hash must match a full recomputation after any sequence of
+	// mutations.
+	set := new(LinkedSet)
+	_ = set.Fingerprint()
+	for range counter(1024) {
+		val := rnd.Intn(32)
+		switch rnd.Intn(8) {
+		case 0, 1:
+			set.Add(val)
+		case 2:
+			set.AddFirst(val)
+		case 3:
+			set.Delete(val)
+		case 4:
+			_, _ = set.PoppedLast()
+		case 5:
+			set.Replace(val, rnd.Intn(32))
+		case 6:
+			_ = set.PoppedFirstN(2)
+		case 7:
+			requireEqual(nil, Apply(set, Diff(set, NewLinkedSet(randomVals(4)...))))
+		}
+		requireEqual(NewSliceSet(set.Values()...).Fingerprint(), set.Fingerprint())
+	}
 
-	set.AddFirst(10)
-	requireEqual([]interface{}{10, 30, 20}, set.Values())
+	requireEqual(nil, set.Scan(`{a,b}`))
+	requireEqual(NewSliceSet(`a`, `b`).Fingerprint(), set.Fingerprint())
+
+	t.Run("equal keys", func(t *T) {
+		negZero := math.Copysign(0, -1)
+		requireEqual(NewLinkedSet(0.0).Fingerprint(), NewSliceSet(negZero).Fingerprint())
+		requireEqual(NewLinkedSet(float32(0)).Fingerprint(), NewSliceSet(float32(negZero)).Fingerprint())
+		requireEqual(NewLinkedSet(complex(0, 0)).Fingerprint(), NewSliceSet(complex(negZero, negZero)).Fingerprint())
+		requireEqual(NewLinkedSet(math.NaN()).Fingerprint(), NewSliceSet(-math.NaN()).Fingerprint())
+
+		type floats struct {
+			F float64
+			A [2]float32
+			I interface{}
+		}
+		requireEqual(
+			NewLinkedSet(floats{0, [2]float32{0, 1}, 0.0}).Fingerprint(),
+			NewSliceSet(floats{negZero, [2]float32{float32(negZero), 1}, negZero}).Fingerprint(),
+		)
+		requireEqual(false, NewLinkedSet(floats{F: 1}).Fingerprint() == NewLinkedSet(floats{F: 2}).Fingerprint())
+		requireEqual(false, NewLinkedSet(floats{I: 1}).Fingerprint() == NewLinkedSet(floats{I: 1.0}).Fingerprint())
+
+		set := NewLinkedSet(0.0, 10)
+		_ = set.Fingerprint()
+		requireEqual(true, set.Replaced(negZero, 20))
+		requireEqual(nil, set.Validate())
+		requireEqual(NewSliceSet(20, 10).Fingerprint(), set.Fingerprint())
+	})
+
+	t.Run("pointers", func(t *T) {
+		one, other := new(int), new(int)
+		requireEqual(false, NewLinkedSet(one).Fingerprint() == NewLinkedSet(other).Fingerprint())
 
-	set.AddFirst(30)
-	requireEqual([]interface{}{30, 10, 20}, set.Values())
+		set := NewLinkedSet(one)
+		_ = set.Fingerprint()
+		*one = 10
+		requireEqual(nil, set.Validate())
+		set.Delete(one)
+		requireEqual(uint64(0), set.Fingerprint())
+	})
 
-	set.AddFirst(30)
-	requireEqual([]interface{}{30, 10, 20}, set.Values())
+	_ = set.Drain()
+	requireEqual(uint64(0), set.Fingerprint())
 }
 
-func testSetAddedFirst(set OrdSet) {
-	requireEqual(0, set.Len())
+func TestTextFormat(t *T) {
+	split := func(format TextFormat, src string) []string {
+		out, err := format.Split(src)
+		if err != nil {
+			panic(err)
+		}
+		return out
+	}
 
-	requireEqual(true, set.AddedFirst(20))
-	requireEqual([]interface{}{20}, set.Values())
+	var format TextFormat
+	requireEqual([]string(nil), split(format, ``))
+	requireEqual([]string(nil), split(format, ` , ,`))
+	requireEqual([]string{`a`}, split(format, `a`))
+	requireEqual([]string{`a`, `b c`, `d`}, split(format, ` a ,b c,, d ,`))
+	requireEqual([]string{`a`, ` d`, `e,f`, `g"h`, ``}, split(format, `a, " d" ,"e,f","g""h",""`))
 
-	requireEqual(false, set.AddedFirst(20))
-	requireEqual([]interface{}{20}, set.Values())
+	for _, src := range []string{`"a`, `"a"b`, `a"b`, `"a""`} {
+		_, err := format.Split(src)
+		requireEqual(true, err != nil)
+	}
 
-	requireEqual(true, set.AddedFirst(10))
-	requireEqual([]interface{}{10, 20}, set.Values())
+	_, err := TextFormat{Sep: '\'', Quote: '\''}.Split(`a`)
+	requireEqual(true, err != nil)
 
-	requireEqual(false, set.AddedFirst(10))
-	requireEqual([]interface{}{10, 20}, set.Values())
+	join := func(format TextFormat, vals ...interface{}) string {
+		out, err := format.Join(vals)
+		if err != nil {
+			panic(err)
+		}
+		return out
+	}
 
-	requireEqual(true, set.AddedFirst(30))
-	requireEqual([]interface{}{30, 10, 20}, set.Values())
+	requireEqual(``, join(format))
+	requireEqual(`a,b c," d","e,f","g""h","",10`, join(format, `a`, `b c`, ` d`, `e,f`, `g"h`, ``, 10))
 
-	requireEqual(false, set.AddedFirst(30))
-	requireEqual([]interface{}{30, 10, 20}, set.Values())
+	custom := TextFormat{Sep: ';', Quote: '\''}
+	requireEqual(`a;b,c;'d;e';'f''g'`, join(custom, `a`, `b,c`, `d;e`, `f'g`))
+	requireEqual([]string{`a`, `b,c`, `d;e`, `f'g`}, split(custom, `a;b,c;'d;e';'f''g'`))
 
-	requireEqual(false, set.AddedFirst(10))
-	requireEqual([]interface{}{10, 30, 20}, set.Values())
+	spaced := TextFormat{Sep: ' '}
+	requireEqual([]string{`a`, `b`, `c d`}, split(spaced, `a  b "c d" `))
+	requireEqual(`a b "c d"`, join(spaced, `a`, `b`, `c d`))
+}
 
-	requireEqual(false, set.AddedFirst(10))
-	requireEqual([]interface{}{10, 30, 20}, set.Values())
+func TestTextSet(t *T) {
+	type textSet interface {
+		OrdSet
+		flag.Value
+		encoding.TextMarshaler
+		encoding.TextUnmarshaler
+	}
 
-	requireEqual(false, set.AddedFirst(30))
-	requireEqual([]interface{}{30, 10, 20}, set.Values())
+	test := func(set textSet) {
+		flags := flag.NewFlagSet(``, flag.ContinueOnError)
+		flags.Var(set, `tag`, ``)
+		requireEqual(nil, flags.Parse([]string{`-tag`, `a`, `-tag`, `b,"c d"`, `-tag`, `a`}))
+		requireEqual([]interface{}{`a`, `b`, `c d`}, set.Values())
+		requireEqual(`[a b c d]`, set.String())
 
-	requireEqual(false, set.AddedFirst(30))
-	requireEqual([]interface{}{30, 10, 20}, set.Values())
-}
+		text, err := set.MarshalText()
+		requireEqual(nil, err)
+		requireEqual(`a,b,c d`, string(text))
 
-func testSetAddLast(set OrdSet) {
-	requireEqual(0, set.Len())
+		requireEqual(nil, set.UnmarshalText([]byte(`c, "a,b" ,c`)))
+		requireEqual([]interface{}{`c`, `a,b`}, set.Values())
 
-	set.AddLast(20)
-	requireEqual([]interface{}{20}, set.Values())
+		text, err = set.MarshalText()
+		requireEqual(nil, err)
+		requireEqual(`c,"a,b"`, string(text))
 
-	set.AddLast(20)
-	requireEqual([]interface{}{20}, set.Values())
+		requireEqual(true, set.UnmarshalText([]byte(`"unterminated`)) != nil)
+		requireEqual([]interface{}{`c`, `a,b`}, set.Values())
 
-	set.AddLast(10)
-	requireEqual([]interface{}{20, 10}, set.Values())
+		requireEqual(nil, set.UnmarshalText(nil))
+		requireEqual(0, set.Len())
+	}
 
-	set.AddLast(10)
-	requireEqual([]interface{}{20, 10}, set.Values())
+	test(new(LinkedSet))
+	test(new(SyncLinkedSet))
+	test(new(SliceSet))
+	test(new(SyncSliceSet))
+	test(new(CowSliceSet))
 
-	set.AddLast(30)
-	requireEqual([]interface{}{20, 10, 30}, set.Values())
+	t.Run("custom", func(t *T) {
+		set := TextSet{OrdSet: NewOrdSet(`x`), TextFormat: TextFormat{Sep: ';'}}
+		requireEqual(nil, set.Set(`a;b,c`))
+		requireEqual([]interface{}{`x`, `a`, `b,c`}, set.Values())
+		requireEqual(`x;a;b,c`, set.String())
 
-	set.AddLast(30)
-	requireEqual([]interface{}{20, 10, 30}, set.Values())
+		requireEqual(nil, set.UnmarshalText([]byte(`d;"e;f"`)))
+		requireEqual([]interface{}{`d`, `e;f`}, set.Values())
 
-	set.AddLast(10)
-	requireEqual([]interface{}{20, 30, 10}, set.Values())
+		text, err := set.MarshalText()
+		requireEqual(nil, err)
+		requireEqual(`d;"e;f"`, string(text))
 
-	set.AddLast(10)
-	requireEqual([]interface{}{20, 30, 10}, set.Values())
+		requireEqual(``, new(TextSet).String())
+	})
+}
 
-	set.AddLast(20)
-	requireEqual([]interface{}{30, 10, 20}, set.Values())
+func TestSqlScan(t *T) {
+	type scanSet interface {
+		OrdSet
+		sql.Scanner
+	}
 
-	set.AddLast(20)
-	requireEqual([]interface{}{30, 10, 20}, set.Values())
-}
+	test := func(newSet func() scanSet) {
+		set := newSet()
 
-func testSetAddedLast(set OrdSet) {
-	requireEqual(0, set.Len())
+		requireEqual(nil, set.Scan(`{a,b,"c d",a,NULL,"NULL","x\\y","q\"q", e }`))
+		requireEqual([]interface{}{`a`, `b`, `c d`, nil, `NULL`, `x\y`, `q"q`, `e`}, set.Values())
 
-	requireEqual(true, set.AddedLast(20))
-	requireEqual([]interface{}{20}, set.Values())
+		requireEqual(nil, set.Scan([]byte(`{}`)))
+		requireEqual(0, set.Len())
 
-	requireEqual(false, set.AddedLast(20))
-	requireEqual([]interface{}{20}, set.Values())
+		requireEqual(nil, set.Scan([]byte(`{b,a}`)))
+		requireEqual([]interface{}{`b`, `a`}, set.Values())
 
-	requireEqual(true, set.AddedLast(10))
-	requireEqual([]interface{}{20, 10}, set.Values())
+		requireEqual(nil, set.Scan(nil))
+		requireEqual(0, set.Len())
 
-	requireEqual(false, set.AddedLast(10))
-	requireEqual([]interface{}{20, 10}, set.Values())
+		requireEqual(nil, set.Scan(`["b", "a", "b", 10, null]`))
+		requireEqual([]interface{}{`b`, `a`, float64(10), nil}, set.Values())
 
-	requireEqual(true, set.AddedLast(30))
-	requireEqual([]interface{}{20, 10, 30}, set.Values())
+		// Like Postgres: whitespace inside unquoted elements is kept, around them
+		// it's trimmed unless escaped.
+		requireEqual(nil, set.Scan(`{ a b , c\  ,"d e" , NULL x,\NULL}`))
+		requireEqual([]interface{}{`a b`, `c `, `d e`, `NULL x`, `NULL`}, set.Values())
 
-	requireEqual(false, set.AddedLast(30))
-	requireEqual([]interface{}{20, 10, 30}, set.Values())
+		requireEqual(nil, set.Scan(`null`))
+		requireEqual(0, set.Len())
 
-	requireEqual(false, set.AddedLast(10))
-	requireEqual([]interface{}{20, 30, 10}, set.Values())
+		set.Add(`x`)
+		for _, src := range []interface{}{
+			10, `a,b`, `{a`, `{"a}`, `{a,}`, `{{a}}`, `{"a"b}`, `{"a" b}`, `{a\}`, `[a]`,
+			`[{"a":1},[1]]`, `["a",[1]]`,
+		} {
+			requireEqual(true, set.Scan(src) != nil)
+		}
+		requireEqual([]interface{}{`x`}, set.Values())
+	}
 
-	requireEqual(false, set.AddedLast(10))
-	requireEqual([]interface{}{20, 30, 10}, set.Values())
+	test(func() scanSet { return new(LinkedSet) })
+	test(func() scanSet { return new(SyncLinkedSet) })
+	test(func() scanSet { return new(SliceSet) })
+	test(func() scanSet { return new(SyncSliceSet) })
+	test(func() scanSet { return new(CowSliceSet) })
 
-	requireEqual(false, set.AddedLast(20))
-	requireEqual([]interface{}{30, 10, 20}, set.Values())
+	t.Run("SliceSet NULL", func(t *T) {
+		var set SliceSet
 
-	requireEqual(false, set.AddedLast(20))
-	requireEqual([]interface{}{30, 10, 20}, set.Values())
-}
+		requireEqual(nil, set.Scan(`{}`))
+		requireEqual(SliceSet{}, set)
 
-func testSetPoppedFirst(set OrdSet) {
-	requireEqual(0, set.Len())
+		requireEqual(nil, set.Scan(`{a}`))
+		requireEqual(nil, set.Scan(nil))
+		requireEqual(SliceSet(nil), set)
 
-	set.Add(20)
-	set.Add(10)
-	set.Add(30)
-	requireEqual([]interface{}{20, 10, 30}, set.Values())
+		requireEqual(nil, set.Scan(`[]`))
+		requireEqual(SliceSet{}, set)
 
-	requireEqual(pair{20, true}, toPair(set.PoppedFirst()))
-	requireEqual([]interface{}{10, 30}, set.Values())
+		requireEqual(nil, set.Scan(`null`))
+		requireEqual(SliceSet(nil), set)
+	})
+}
 
-	requireEqual(pair{10, true}, toPair(set.PoppedFirst()))
-	requireEqual([]interface{}{30}, set.Values())
+func TestSqlValue(t *T) {
+	test := func(newSet func(...interface{}) driver.Valuer, nilSet driver.Valuer) {
+		requireEqual(nil, toDriverValue(nilSet))
+		requireEqual(`{}`, toDriverValue(newSet()))
+		requireEqual(`{a,10,NULL}`, toDriverValue(newSet(`a`, 10, nil)))
+		requireEqual(
+			`{"c d","","NULL","x\\y","q\"q","{}","a,b"}`,
+			toDriverValue(newSet(`c d`, ``, `NULL`, `x\y`, `q"q`, `{}`, `a,b`)),
+		)
+	}
 
-	requireEqual(pair{30, true}, toPair(set.PoppedFirst()))
-	requireEqual([]interface{}{}, set.Values())
+	test(func(vals ...interface{}) driver.Valuer { return NewLinkedSet(vals...) }, (*LinkedSet)(nil))
+	test(func(vals ...interface{}) driver.Valuer { return NewSyncLinkedSet(vals...) }, (*SyncLinkedSet)(nil))
+	test(func(vals ...interface{}) driver.Valuer {
+		set := SliceSet{}
+		for _, val := range vals {
+			set.Add(val)
+		}
+		return &set
+	}, (*SliceSet)(nil))
+	test(func(vals ...interface{}) driver.Valuer { return NewSyncSliceSet(vals...) }, (*SyncSliceSet)(nil))
+	test(func(vals ...interface{}) driver.Valuer { return NewCowSliceSet(vals...) }, (*CowSliceSet)(nil))
+
+	// A nil slice is SQL `NULL`.
+	requireEqual(nil, toDriverValue(new(SliceSet)))
+}
 
-	requireEqual(pair{nil, false}, toPair(set.PoppedFirst()))
-	requireEqual([]interface{}{}, set.Values())
+// Round trip through `database/sql` using a fake driver that echoes its first
+// argument back as a single-column row, the way Postgres returns arrays.
+func TestSqlRoundTrip(t *T) {
+	db, err := sql.Open(`gord_echo`, ``)
+	requireEqual(nil, err)
+	defer db.Close()
+
+	src := NewLinkedSet(`b`, `a`, `c d`, `q"q`)
+
+	var out LinkedSet
+	requireEqual(nil, db.QueryRow(`select $1`, src).Scan(&out))
+	requireEqual([]interface{}{`b`, `a`, `c d`, `q"q`}, out.Values())
+
+	var empty SliceSet
+	requireEqual(nil, db.QueryRow(`select $1`, &SliceSet{}).Scan(&empty))
+	requireEqual(SliceSet{}, empty)
+
+	// `NULL` survives a round trip through a `SliceSet` stored by value, but not
+	// through a `LinkedSet`.
+	nullSlice := SliceSet{`a`}
+	requireEqual(nil, db.QueryRow(`select $1`, new(SliceSet)).Scan(&nullSlice))
+	requireEqual(SliceSet(nil), nullSlice)
+	requireEqual(nil, toDriverValue(&nullSlice))
+
+	var nullLinked LinkedSet
+	requireEqual(nil, db.QueryRow(`select $1`, new(SliceSet)).Scan(&nullLinked))
+	requireEqual(`{}`, toDriverValue(&nullLinked))
+
+	var null *SliceSet
+	requireEqual(nil, db.QueryRow(`select $1`, (*SyncLinkedSet)(nil)).Scan(&null))
+	requireEqual((*SliceSet)(nil), null)
+
+	var nonNull *SliceSet
+	requireEqual(nil, db.QueryRow(`select $1`, new(SyncLinkedSet)).Scan(&nonNull))
+	requireEqual(true, nonNull != nil)
 }
 
-func testSetPoppedLast(set OrdSet) {
-	requireEqual(0, set.Len())
+func toDriverValue(val driver.Valuer) driver.Value {
+	out, err := val.Value()
+	if err != nil {
+		panic(err)
+	}
+	return out
+}
 
-	set.Add(20)
-	set.Add(10)
-	set.Add(30)
-	requireEqual([]interface{}{20, 10, 30}, set.Values())
+func init() { sql.Register(`gord_echo`, echoDriver{}) }
 
-	requireEqual(pair{30, true}, toPair(set.PoppedLast()))
-	requireEqual([]interface{}{20, 10}, set.Values())
+type echoDriver struct{}
 
-	requireEqual(pair{10, true}, toPair(set.PoppedLast()))
-	requireEqual([]interface{}{20}, set.Values())
+func (echoDriver) Open(string) (driver.Conn, error) { return echoConn{}, nil }
 
-	requireEqual(pair{20, true}, toPair(set.PoppedLast()))
-	requireEqual([]interface{}{}, set.Values())
+type echoConn struct{}
 
-	requireEqual(pair{nil, false}, toPair(set.PoppedLast()))
-	requireEqual([]interface{}{}, set.Values())
-}
+func (echoConn) Prepare(string) (driver.Stmt, error) { return echoStmt{}, nil }
+func (echoConn) Close() error                        { return nil }
+func (echoConn) Begin() (driver.Tx, error)           { return nil, fmt.Errorf(`unsupported`) }
 
-func testSetValues(set OrdSet) {
-	requireEqual(0, set.Len())
+type echoStmt struct{}
 
-	set.Add(20)
-	set.Add(10)
-	set.Add(30)
-	requireEqual([]interface{}{20, 10, 30}, set.Values())
+func (echoStmt) Close() error  { return nil }
+func (echoStmt) NumInput() int { return 1 }
+
+func (echoStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, fmt.Errorf(`unsupported`)
 }
 
-func testSetNew(new func(...interface{}) OrdSet) {
-	requireEqual(0, new().Len())
-	requireEqual([]interface{}{20}, new(20).Values())
-	requireEqual([]interface{}{20, 10}, new(20, 10).Values())
-	requireEqual([]interface{}{20, 10, 30}, new(20, 10, 30).Values())
-	requireEqual([]interface{}{20, 10, 30}, new(20, 10, 30, 20, 30, 10).Values())
+func (echoStmt) Query(args []driver.Value) (driver.Rows, error) {
+	val := args[0]
+	if text, ok := val.(string); ok {
+		val = []byte(text)
+	}
+	return &echoRows{val: val}, nil
 }
 
-func testSetString(set StringerOrdSet) {
-	requireEqual(`[]`, set.String())
+type echoRows struct {
+	val  driver.Value
+	done bool
+}
 
-	set.Add(20)
-	requireEqual(`[20]`, set.String())
+func (*echoRows) Columns() []string { return []string{`val`} }
+func (*echoRows) Close() error      { return nil }
 
-	set.Add(10)
-	set.Add(30)
-	requireEqual(`[20 10 30]`, set.String())
+func (self *echoRows) Next(dest []driver.Value) error {
+	if self.done {
+		return io.EOF
+	}
+	self.done = true
+	dest[0] = self.val
+	return nil
 }
 
-// Applies to both `LinkedSet` and `SyncLinkedSet`.
-func testLinkedSetGoString(set StringerOrdSet, constr string) {
-	requireEqual(constr+`()`, set.GoString())
+func BenchmarkLinkedSet(b *B)        { bench(b, func() OrdSet { return new(LinkedSet) }, boxInt) }
+func BenchmarkSyncLinkedSet(b *B)    { bench(b, func() OrdSet { return new(SyncLinkedSet) }, boxInt) }
+func BenchmarkSliceSet(b *B)         { bench(b, func() OrdSet { return new(SliceSet) }, boxInt) }
+func BenchmarkLinkedIntSet(b *B)     { bench(b, func() *LinkedIntSet { return new(LinkedIntSet) }, intVal) }
+func BenchmarkLinkedSetStrings(b *B) { bench(b, func() OrdSet { return new(LinkedSet) }, boxString) }
+func BenchmarkLinkedStringSet(b *B) {
+	bench(b, func() *LinkedStringSet { return new(LinkedStringSet) }, strconv.Itoa)
+}
 
-	set.Add(20)
-	requireEqual(constr+`(20)`, set.GoString())
+// Building a large set from scratch is where boxing costs the most: one
+// allocation per value, plus garbage collection.
+func BenchmarkLinkedSetFill(b *B) { benchFill(b, func() OrdSet { return new(LinkedSet) }, boxInt) }
+func BenchmarkLinkedIntSetFill(b *B) {
+	benchFill(b, func() *LinkedIntSet { return new(LinkedIntSet) }, intVal)
+}
 
-	set.Add(10)
-	set.Add(30)
-	requireEqual(constr+`(20, 10, 30)`, set.GoString())
+// Subset of `OrdSet` exercised by benchmarks, parametrized so that typed sets
+// such as `LinkedIntSet` can be measured without boxing. `OrdSet` satisfies
+// `benchSet[interface{}]`.
+type benchSet[V any] interface {
+	Add(V)
+	Has(V) bool
+	AddFirst(V)
+	AddLast(V)
+	Delete(V)
+	PoppedFirst() (V, bool)
+	PoppedLast() (V, bool)
 }
 
-func BenchmarkLinkedSet(b *B)     { bench(b, func() OrdSet { return new(LinkedSet) }) }
-func BenchmarkSyncLinkedSet(b *B) { bench(b, func() OrdSet { return new(SyncLinkedSet) }) }
-func BenchmarkSliceSet(b *B)      { bench(b, func() OrdSet { return new(SliceSet) }) }
+func boxInt(val int) interface{}    { return val }
+func boxString(val int) interface{} { return strconv.Itoa(val) }
+func intVal(val int) int            { return val }
 
-func bench(b *B, newSet func() OrdSet) {
-	b.Run("small", func(b *B) { benchSized(b, newSet, 1<<3) })
-	b.Run("bigger", func(b *B) { benchSized(b, newSet, 1<<8) })
-	b.Run("more bigger", func(b *B) { benchSized(b, newSet, 1<<12) })
-	b.Run("MORE bigger", func(b *B) { benchSized(b, newSet, 1<<16) })
+func bench[S benchSet[V], V any](b *B, newSet func() S, toVal func(int) V) {
+	b.Run("small", func(b *B) { benchSized(b, newSet, toVal, 1<<3) })
+	b.Run("bigger", func(b *B) { benchSized(b, newSet, toVal, 1<<8) })
+	b.Run("more bigger", func(b *B) { benchSized(b, newSet, toVal, 1<<12) })
+	b.Run("MORE bigger", func(b *B) { benchSized(b, newSet, toVal, 1<<16) })
 }
 
 // Caution: this benchmark may be misleading. It combines MULTIPLE operations
@@ -549,8 +3253,8 @@ func bench(b *B, newSet func() OrdSet) {
 // This benchmark's main purpose is to spot worse-case performance degradation,
 // like `SliceSet`'s behavior with large data sets, and identify the breakpoint
 // sizes.
-func benchSized(b *B, newSet func() OrdSet, size int) {
-	set, first, mid, last, next := benchInit(newSet, size)
+func benchSized[S benchSet[V], V any](b *B, newSet func() S, toVal func(int) V, size int) {
+	set, first, mid, last, next := benchInit(newSet, toVal, size)
 	b.ResetTimer()
 
 	for range counter(b.N) {
@@ -584,25 +3288,41 @@ func benchSized(b *B, newSet func() OrdSet, size int) {
 	}
 }
 
-func benchInit(
-	newSet func() OrdSet, size int,
+func benchInit[S benchSet[V], V any](
+	newSet func() S, toVal func(int) V, size int,
 ) (
-	set OrdSet, first interface{}, mid interface{}, last interface{}, next interface{},
+	set S, first V, mid V, last V, next V,
 ) {
 	set = newSet()
 
 	vals := rand.Perm(size)
 	for _, val := range vals {
-		set.Add(val)
+		set.Add(toVal(val))
 	}
 
-	first = vals[0]
-	mid = vals[len(vals)/2]
-	last = vals[len(vals)-1]
-	next = rand.Int()
+	first = toVal(vals[0])
+	mid = toVal(vals[len(vals)/2])
+	last = toVal(vals[len(vals)-1])
+	next = toVal(rand.Int())
 	return
 }
 
+func benchFill[S benchSet[V], V any](b *B, newSet func() S, toVal func(int) V) {
+	vals := make([]V, 1<<16)
+	for i := range vals {
+		vals[i] = toVal(i)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for range counter(b.N) {
+		set := newSet()
+		for _, val := range vals {
+			set.Add(val)
+		}
+	}
+}
+
 func counter(n int) []struct{} { return make([]struct{}, n) }
 
 // Note: the panic makes a stack trace, convenient for finding the line.
@@ -616,6 +3336,21 @@ actual:   %#v
 	}
 }
 
+func try(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
+
+func requirePanic(fun func()) {
+	defer func() {
+		if recover() == nil {
+			panic(fmt.Errorf(`expected a panic`))
+		}
+	}()
+	fun()
+}
+
 func shuffled(list []int) []int {
 	out := make([]int, len(list))
 	copy(out, list)
@@ -629,6 +3364,14 @@ func shuffle(list []int) {
 	})
 }
 
+func callSet(set interface{}, name string, args ...interface{}) interface{} {
+	inputs := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		inputs[i] = reflect.ValueOf(arg)
+	}
+	return reflect.ValueOf(set).MethodByName(name).Call(inputs)[0].Interface()
+}
+
 func toPair(val interface{}, did bool) pair {
 	return pair{val, did}
 }
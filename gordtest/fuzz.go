@@ -0,0 +1,26 @@
+package gordtest
+
+import (
+	"testing"
+
+	"github.com/mitranim/gord"
+)
+
+/*
+Fuzz harness. Interprets fuzzer input as a sequence of operations, and compares
+every result, and the resulting contents after every operation, to those of
+`gord.LinkedSet`. Must be called from a fuzz test:
+
+	func FuzzMySet(f *testing.F) {
+		gordtest.Fuzz(f, func() gord.OrdSet { return NewMySet() })
+	}
+*/
+func Fuzz(f *testing.F, newSet func() gord.OrdSet) {
+	f.Add([]byte{})
+	f.Add([]byte{0, 1, 0, 2, 4, 2, 6, 1, 2, 1, 8, 0, 9, 0})
+	f.Add([]byte{4, 3, 4, 2, 4, 1, 14, 2, 15, 2, 6, 3, 10, 0, 11, 0})
+
+	f.Fuzz(func(t *testing.T, ops []byte) {
+		Check(t, newSet(), new(gord.LinkedSet), ops)
+	})
+}
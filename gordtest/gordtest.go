@@ -0,0 +1,490 @@
+/*
+Conformance tests for implementations of `gord.OrdSet`, for use in your own
+tests. Example:
+
+	func TestMySet(t *testing.T) {
+		gordtest.TestOrdSet(t, func() gord.OrdSet { return NewMySet() })
+		gordtest.TestRandom(t, func() gord.OrdSet { return NewMySet() })
+	}
+
+	func FuzzMySet(f *testing.F) {
+		gordtest.Fuzz(f, func() gord.OrdSet { return NewMySet() })
+	}
+
+Run the fuzzer with `go test -fuzz FuzzMySet`.
+*/
+package gordtest
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mitranim/gord"
+)
+
+/*
+Runs the `gord.OrdSet` contract against sets created by the given function,
+which must return a new, empty set on every call. Each method is tested in its
+own subtest. If the sets also implement `gord.NavOrdSet`, its methods are
+tested too. The tests use small integers as values.
+*/
+func TestOrdSet(t *testing.T, newSet func() gord.OrdSet) {
+	t.Run("Len", func(t *testing.T) { testLen(t, newSet()) })
+	t.Run("Has", func(t *testing.T) { testHas(t, newSet()) })
+	t.Run("Add", func(t *testing.T) { testAdd(t, newSet()) })
+	t.Run("Added", func(t *testing.T) { testAdded(t, newSet()) })
+	t.Run("Delete", func(t *testing.T) { testDelete(t, newSet()) })
+	t.Run("Deleted", func(t *testing.T) { testDeleted(t, newSet()) })
+	t.Run("AddFirst", func(t *testing.T) { testAddFirst(t, newSet()) })
+	t.Run("AddedFirst", func(t *testing.T) { testAddedFirst(t, newSet()) })
+	t.Run("AddLast", func(t *testing.T) { testAddLast(t, newSet()) })
+	t.Run("AddedLast", func(t *testing.T) { testAddedLast(t, newSet()) })
+	t.Run("PoppedFirst", func(t *testing.T) { testPoppedFirst(t, newSet()) })
+	t.Run("PoppedLast", func(t *testing.T) { testPoppedLast(t, newSet()) })
+	t.Run("Values", func(t *testing.T) { testValues(t, newSet()) })
+
+	_, ok := newSet().(gord.NavOrdSet)
+	if !ok {
+		return
+	}
+	newNav := func() gord.NavOrdSet { return newSet().(gord.NavOrdSet) }
+	t.Run("First", func(t *testing.T) { testFirst(t, newNav()) })
+	t.Run("Last", func(t *testing.T) { testLast(t, newNav()) })
+	t.Run("Next", func(t *testing.T) { testNext(t, newNav()) })
+	t.Run("Prev", func(t *testing.T) { testPrev(t, newNav()) })
+}
+
+// Relies on correctness of `Add`, which is tested later.
+func testLen(t testing.TB, set gord.OrdSet) {
+	equal(t, 0, set.Len())
+
+	set.Add(20)
+	equal(t, 1, set.Len())
+	equal(t, []interface{}{20}, set.Values())
+
+	set.Add(20)
+	equal(t, 1, set.Len())
+	equal(t, []interface{}{20}, set.Values())
+
+	set.Add(10)
+	equal(t, 2, set.Len())
+	equal(t, []interface{}{20, 10}, set.Values())
+
+	set.Add(10)
+	equal(t, 2, set.Len())
+	equal(t, []interface{}{20, 10}, set.Values())
+
+	set.Add(30)
+	equal(t, 3, set.Len())
+	equal(t, []interface{}{20, 10, 30}, set.Values())
+
+	set.Add(30)
+	equal(t, 3, set.Len())
+	equal(t, []interface{}{20, 10, 30}, set.Values())
+}
+
+func testHas(t testing.TB, set gord.OrdSet) {
+	equal(t, 0, set.Len())
+
+	set.Add(20)
+	equal(t, true, set.Has(20))
+	equal(t, false, set.Has(10))
+	equal(t, false, set.Has(30))
+
+	set.Add(20)
+	equal(t, true, set.Has(20))
+	equal(t, false, set.Has(10))
+	equal(t, false, set.Has(30))
+
+	set.Add(10)
+	equal(t, true, set.Has(20))
+	equal(t, true, set.Has(10))
+	equal(t, false, set.Has(30))
+
+	set.Add(10)
+	equal(t, true, set.Has(20))
+	equal(t, true, set.Has(10))
+	equal(t, false, set.Has(30))
+
+	set.Add(30)
+	equal(t, true, set.Has(20))
+	equal(t, true, set.Has(10))
+	equal(t, true, set.Has(30))
+
+	set.Add(30)
+	equal(t, true, set.Has(20))
+	equal(t, true, set.Has(10))
+	equal(t, true, set.Has(30))
+}
+
+func testAdd(t testing.TB, set gord.OrdSet) {
+	equal(t, 0, set.Len())
+
+	set.Add(20)
+	equal(t, []interface{}{20}, set.Values())
+
+	set.Add(20)
+	equal(t, []interface{}{20}, set.Values())
+
+	set.Add(10)
+	equal(t, []interface{}{20, 10}, set.Values())
+
+	set.Add(10)
+	equal(t, []interface{}{20, 10}, set.Values())
+
+	set.Add(30)
+	equal(t, []interface{}{20, 10, 30}, set.Values())
+
+	set.Add(30)
+	equal(t, []interface{}{20, 10, 30}, set.Values())
+}
+
+func testAdded(t testing.TB, set gord.OrdSet) {
+	equal(t, 0, set.Len())
+
+	equal(t, true, set.Added(20))
+	equal(t, []interface{}{20}, set.Values())
+
+	equal(t, false, set.Added(20))
+	equal(t, []interface{}{20}, set.Values())
+
+	equal(t, true, set.Added(10))
+	equal(t, []interface{}{20, 10}, set.Values())
+
+	equal(t, false, set.Added(10))
+	equal(t, []interface{}{20, 10}, set.Values())
+
+	equal(t, true, set.Added(30))
+	equal(t, []interface{}{20, 10, 30}, set.Values())
+
+	equal(t, false, set.Added(30))
+	equal(t, []interface{}{20, 10, 30}, set.Values())
+}
+
+func testDelete(t testing.TB, set gord.OrdSet) {
+	equal(t, 0, set.Len())
+
+	set.Add(20)
+	set.Add(10)
+	set.Add(30)
+	equal(t, []interface{}{20, 10, 30}, set.Values())
+
+	set.Delete(10)
+	equal(t, []interface{}{20, 30}, set.Values())
+
+	set.Delete(10)
+	equal(t, []interface{}{20, 30}, set.Values())
+
+	set.Delete(20)
+	equal(t, []interface{}{30}, set.Values())
+
+	set.Delete(20)
+	equal(t, []interface{}{30}, set.Values())
+
+	set.Delete(30)
+	equal(t, []interface{}{}, set.Values())
+
+	set.Delete(30)
+	equal(t, []interface{}{}, set.Values())
+}
+
+func testDeleted(t testing.TB, set gord.OrdSet) {
+	equal(t, 0, set.Len())
+
+	set.Add(20)
+	set.Add(10)
+	set.Add(30)
+	equal(t, []interface{}{20, 10, 30}, set.Values())
+
+	equal(t, true, set.Deleted(10))
+	equal(t, []interface{}{20, 30}, set.Values())
+
+	equal(t, false, set.Deleted(10))
+	equal(t, []interface{}{20, 30}, set.Values())
+
+	equal(t, true, set.Deleted(20))
+	equal(t, []interface{}{30}, set.Values())
+
+	equal(t, false, set.Deleted(20))
+	equal(t, []interface{}{30}, set.Values())
+
+	equal(t, true, set.Deleted(30))
+	equal(t, []interface{}{}, set.Values())
+
+	equal(t, false, set.Deleted(30))
+	equal(t, []interface{}{}, set.Values())
+}
+
+func testAddFirst(t testing.TB, set gord.OrdSet) {
+	equal(t, 0, set.Len())
+
+	set.AddFirst(20)
+	equal(t, []interface{}{20}, set.Values())
+
+	set.AddFirst(20)
+	equal(t, []interface{}{20}, set.Values())
+
+	set.AddFirst(10)
+	equal(t, []interface{}{10, 20}, set.Values())
+
+	set.AddFirst(10)
+	equal(t, []interface{}{10, 20}, set.Values())
+
+	set.AddFirst(30)
+	equal(t, []interface{}{30, 10, 20}, set.Values())
+
+	set.AddFirst(30)
+	equal(t, []interface{}{30, 10, 20}, set.Values())
+
+	set.AddFirst(10)
+	equal(t, []interface{}{10, 30, 20}, set.Values())
+
+	set.AddFirst(10)
+	equal(t, []interface{}{10, 30, 20}, set.Values())
+
+	set.AddFirst(30)
+	equal(t, []interface{}{30, 10, 20}, set.Values())
+
+	set.AddFirst(30)
+	equal(t, []interface{}{30, 10, 20}, set.Values())
+}
+
+func testAddedFirst(t testing.TB, set gord.OrdSet) {
+	equal(t, 0, set.Len())
+
+	equal(t, true, set.AddedFirst(20))
+	equal(t, []interface{}{20}, set.Values())
+
+	equal(t, false, set.AddedFirst(20))
+	equal(t, []interface{}{20}, set.Values())
+
+	equal(t, true, set.AddedFirst(10))
+	equal(t, []interface{}{10, 20}, set.Values())
+
+	equal(t, false, set.AddedFirst(10))
+	equal(t, []interface{}{10, 20}, set.Values())
+
+	equal(t, true, set.AddedFirst(30))
+	equal(t, []interface{}{30, 10, 20}, set.Values())
+
+	equal(t, false, set.AddedFirst(30))
+	equal(t, []interface{}{30, 10, 20}, set.Values())
+
+	equal(t, false, set.AddedFirst(10))
+	equal(t, []interface{}{10, 30, 20}, set.Values())
+
+	equal(t, false, set.AddedFirst(10))
+	equal(t, []interface{}{10, 30, 20}, set.Values())
+
+	equal(t, false, set.AddedFirst(30))
+	equal(t, []interface{}{30, 10, 20}, set.Values())
+
+	equal(t, false, set.AddedFirst(30))
+	equal(t, []interface{}{30, 10, 20}, set.Values())
+}
+
+func testAddLast(t testing.TB, set gord.OrdSet) {
+	equal(t, 0, set.Len())
+
+	set.AddLast(20)
+	equal(t, []interface{}{20}, set.Values())
+
+	set.AddLast(20)
+	equal(t, []interface{}{20}, set.Values())
+
+	set.AddLast(10)
+	equal(t, []interface{}{20, 10}, set.Values())
+
+	set.AddLast(10)
+	equal(t, []interface{}{20, 10}, set.Values())
+
+	set.AddLast(30)
+	equal(t, []interface{}{20, 10, 30}, set.Values())
+
+	set.AddLast(30)
+	equal(t, []interface{}{20, 10, 30}, set.Values())
+
+	set.AddLast(10)
+	equal(t, []interface{}{20, 30, 10}, set.Values())
+
+	set.AddLast(10)
+	equal(t, []interface{}{20, 30, 10}, set.Values())
+
+	set.AddLast(20)
+	equal(t, []interface{}{30, 10, 20}, set.Values())
+
+	set.AddLast(20)
+	equal(t, []interface{}{30, 10, 20}, set.Values())
+}
+
+func testAddedLast(t testing.TB, set gord.OrdSet) {
+	equal(t, 0, set.Len())
+
+	equal(t, true, set.AddedLast(20))
+	equal(t, []interface{}{20}, set.Values())
+
+	equal(t, false, set.AddedLast(20))
+	equal(t, []interface{}{20}, set.Values())
+
+	equal(t, true, set.AddedLast(10))
+	equal(t, []interface{}{20, 10}, set.Values())
+
+	equal(t, false, set.AddedLast(10))
+	equal(t, []interface{}{20, 10}, set.Values())
+
+	equal(t, true, set.AddedLast(30))
+	equal(t, []interface{}{20, 10, 30}, set.Values())
+
+	equal(t, false, set.AddedLast(30))
+	equal(t, []interface{}{20, 10, 30}, set.Values())
+
+	equal(t, false, set.AddedLast(10))
+	equal(t, []interface{}{20, 30, 10}, set.Values())
+
+	equal(t, false, set.AddedLast(10))
+	equal(t, []interface{}{20, 30, 10}, set.Values())
+
+	equal(t, false, set.AddedLast(20))
+	equal(t, []interface{}{30, 10, 20}, set.Values())
+
+	equal(t, false, set.AddedLast(20))
+	equal(t, []interface{}{30, 10, 20}, set.Values())
+}
+
+func testPoppedFirst(t testing.TB, set gord.OrdSet) {
+	equal(t, 0, set.Len())
+
+	set.Add(20)
+	set.Add(10)
+	set.Add(30)
+	equal(t, []interface{}{20, 10, 30}, set.Values())
+
+	equal(t, pair{20, true}, toPair(set.PoppedFirst()))
+	equal(t, []interface{}{10, 30}, set.Values())
+
+	equal(t, pair{10, true}, toPair(set.PoppedFirst()))
+	equal(t, []interface{}{30}, set.Values())
+
+	equal(t, pair{30, true}, toPair(set.PoppedFirst()))
+	equal(t, []interface{}{}, set.Values())
+
+	equal(t, pair{nil, false}, toPair(set.PoppedFirst()))
+	equal(t, []interface{}{}, set.Values())
+}
+
+func testPoppedLast(t testing.TB, set gord.OrdSet) {
+	equal(t, 0, set.Len())
+
+	set.Add(20)
+	set.Add(10)
+	set.Add(30)
+	equal(t, []interface{}{20, 10, 30}, set.Values())
+
+	equal(t, pair{30, true}, toPair(set.PoppedLast()))
+	equal(t, []interface{}{20, 10}, set.Values())
+
+	equal(t, pair{10, true}, toPair(set.PoppedLast()))
+	equal(t, []interface{}{20}, set.Values())
+
+	equal(t, pair{20, true}, toPair(set.PoppedLast()))
+	equal(t, []interface{}{}, set.Values())
+
+	equal(t, pair{nil, false}, toPair(set.PoppedLast()))
+	equal(t, []interface{}{}, set.Values())
+}
+
+func testValues(t testing.TB, set gord.OrdSet) {
+	equal(t, 0, set.Len())
+
+	set.Add(20)
+	set.Add(10)
+	set.Add(30)
+	equal(t, []interface{}{20, 10, 30}, set.Values())
+}
+
+func testFirst(t testing.TB, set gord.NavOrdSet) {
+	equal(t, pair{nil, false}, toPair(set.First()))
+
+	set.Add(20)
+	equal(t, pair{20, true}, toPair(set.First()))
+
+	set.Add(10)
+	set.Add(30)
+	equal(t, pair{20, true}, toPair(set.First()))
+	equal(t, []interface{}{20, 10, 30}, set.Values())
+
+	set.AddFirst(30)
+	equal(t, pair{30, true}, toPair(set.First()))
+}
+
+func testLast(t testing.TB, set gord.NavOrdSet) {
+	equal(t, pair{nil, false}, toPair(set.Last()))
+
+	set.Add(20)
+	equal(t, pair{20, true}, toPair(set.Last()))
+
+	set.Add(10)
+	set.Add(30)
+	equal(t, pair{30, true}, toPair(set.Last()))
+	equal(t, []interface{}{20, 10, 30}, set.Values())
+
+	set.AddLast(20)
+	equal(t, pair{20, true}, toPair(set.Last()))
+}
+
+func testNext(t testing.TB, set gord.NavOrdSet) {
+	equal(t, pair{nil, false}, toPair(set.Next(20)))
+
+	set.Add(20)
+	set.Add(10)
+	set.Add(30)
+
+	equal(t, pair{10, true}, toPair(set.Next(20)))
+	equal(t, pair{30, true}, toPair(set.Next(10)))
+	equal(t, pair{nil, false}, toPair(set.Next(30)))
+	equal(t, pair{nil, false}, toPair(set.Next(40)))
+	equal(t, []interface{}{20, 10, 30}, set.Values())
+}
+
+func testPrev(t testing.TB, set gord.NavOrdSet) {
+	equal(t, pair{nil, false}, toPair(set.Prev(20)))
+
+	set.Add(20)
+	set.Add(10)
+	set.Add(30)
+
+	equal(t, pair{nil, false}, toPair(set.Prev(20)))
+	equal(t, pair{20, true}, toPair(set.Prev(10)))
+	equal(t, pair{10, true}, toPair(set.Prev(30)))
+	equal(t, pair{nil, false}, toPair(set.Prev(40)))
+	equal(t, []interface{}{20, 10, 30}, set.Values())
+}
+
+// Fails the test unless the values are deeply equal. Considers nil and empty
+// slices equal, since `gord.OrdSet.Values` is allowed to return either.
+func equal(t testing.TB, expected, actual interface{}) {
+	t.Helper()
+	if !reflect.DeepEqual(normalize(expected), normalize(actual)) {
+		t.Fatalf(`
+failed equality check
+expected: %#v
+actual:   %#v
+`, expected, actual)
+	}
+}
+
+func normalize(val interface{}) interface{} {
+	if vals, ok := val.([]interface{}); ok && len(vals) == 0 {
+		return []interface{}(nil)
+	}
+	return val
+}
+
+func toPair(val interface{}, did bool) pair {
+	return pair{val, did}
+}
+
+type pair struct {
+	val interface{}
+	did bool
+}
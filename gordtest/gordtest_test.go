@@ -0,0 +1,56 @@
+package gordtest_test
+
+import (
+	"testing"
+
+	"github.com/mitranim/gord"
+	"github.com/mitranim/gord/gordtest"
+)
+
+type (
+	T = testing.T
+	F = testing.F
+)
+
+func TestModel(t *T) {
+	gordtest.TestOrdSet(t, func() gord.OrdSet { return new(gordtest.Model) })
+}
+
+// The model must agree with `LinkedSet`, which is the fuzzing reference.
+func FuzzModel(f *F) {
+	gordtest.Fuzz(f, func() gord.OrdSet { return new(gordtest.Model) })
+}
+
+// A deliberately broken set must be caught by the random test.
+func TestCheckCatchesBugs(t *T) {
+	var fake fakeT
+	func() {
+		defer func() { _ = recover() }()
+		gordtest.Check(&fake, new(brokenSet), new(gordtest.Model), []byte{0, 1, 0, 2, 5, 2})
+	}()
+	if !fake.failed {
+		t.Fatal(`expected Check to report the broken AddedFirst`)
+	}
+}
+
+// Forgets to move existing values to the front.
+type brokenSet struct{ gordtest.Model }
+
+func (self *brokenSet) AddedFirst(val interface{}) bool {
+	if self.Has(val) {
+		return false
+	}
+	return self.Model.AddedFirst(val)
+}
+
+type fakeT struct {
+	testing.TB
+	failed bool
+}
+
+func (*fakeT) Helper() {}
+
+func (self *fakeT) Fatalf(string, ...interface{}) {
+	self.failed = true
+	panic(`fatal`)
+}
@@ -0,0 +1,147 @@
+package gordtest
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/mitranim/gord"
+)
+
+/*
+Reference implementation of `gord.OrdSet`, written for obviousness rather than
+performance: every operation copies or scans a plain slice. Used by `TestRandom`
+as the source of truth, and can be used directly in your own differential tests.
+A zero value is ready to use.
+*/
+type Model struct{ vals []interface{} }
+
+var _ gord.NavOrdSet = (*Model)(nil)
+
+// Satisfy `gord.Set`.
+func (self *Model) Len() int { return len(self.vals) }
+
+// Satisfy `gord.Set`.
+func (self *Model) Has(val interface{}) bool { return self.index(val) >= 0 }
+
+// Satisfy `gord.Set`.
+func (self *Model) Add(val interface{}) { _ = self.Added(val) }
+
+// Satisfy `gord.Set`.
+func (self *Model) Added(val interface{}) bool {
+	if self.Has(val) {
+		return false
+	}
+	self.vals = append(self.vals, val)
+	return true
+}
+
+// Satisfy `gord.Set`.
+func (self *Model) Delete(val interface{}) { _ = self.Deleted(val) }
+
+// Satisfy `gord.Set`.
+func (self *Model) Deleted(val interface{}) bool {
+	index := self.index(val)
+	if index < 0 {
+		return false
+	}
+	self.vals = without(self.vals, index)
+	return true
+}
+
+// Satisfy `gord.OrdSet`.
+func (self *Model) AddFirst(val interface{}) { _ = self.AddedFirst(val) }
+
+// Satisfy `gord.OrdSet`.
+func (self *Model) AddedFirst(val interface{}) bool {
+	added := !self.Deleted(val)
+	self.vals = append([]interface{}{val}, self.vals...)
+	return added
+}
+
+// Satisfy `gord.OrdSet`.
+func (self *Model) AddLast(val interface{}) { _ = self.AddedLast(val) }
+
+// Satisfy `gord.OrdSet`.
+func (self *Model) AddedLast(val interface{}) bool {
+	added := !self.Deleted(val)
+	self.vals = append(self.vals, val)
+	return added
+}
+
+// Satisfy `gord.OrdSet`.
+func (self *Model) PoppedFirst() (interface{}, bool) {
+	val, ok := self.First()
+	if ok {
+		self.vals = without(self.vals, 0)
+	}
+	return val, ok
+}
+
+// Satisfy `gord.OrdSet`.
+func (self *Model) PoppedLast() (interface{}, bool) {
+	val, ok := self.Last()
+	if ok {
+		self.vals = without(self.vals, len(self.vals)-1)
+	}
+	return val, ok
+}
+
+// Satisfy `gord.OrdSet`.
+func (self *Model) First() (interface{}, bool) { return self.at(0) }
+
+// Satisfy `gord.OrdSet`.
+func (self *Model) Last() (interface{}, bool) { return self.at(len(self.vals) - 1) }
+
+// Satisfy `gord.OrdSet`.
+func (self *Model) Next(val interface{}) (interface{}, bool) {
+	index := self.index(val)
+	if index < 0 {
+		return nil, false
+	}
+	return self.at(index + 1)
+}
+
+// Satisfy `gord.OrdSet`.
+func (self *Model) Prev(val interface{}) (interface{}, bool) {
+	index := self.index(val)
+	if index < 0 {
+		return nil, false
+	}
+	return self.at(index - 1)
+}
+
+// Satisfy `gord.OrdSet`. Returns a copy.
+func (self *Model) Values() []interface{} {
+	return append([]interface{}{}, self.vals...)
+}
+
+// Prints the values, like a slice.
+func (self *Model) String() string { return fmt.Sprint(self.vals) }
+
+func (self *Model) index(val interface{}) int {
+	for i, value := range self.vals {
+		if value == val {
+			return i
+		}
+	}
+	return -1
+}
+
+func (self *Model) at(index int) (interface{}, bool) {
+	if index < 0 || index >= len(self.vals) {
+		return nil, false
+	}
+	return self.vals[index], true
+}
+
+// Never mutates the input.
+func without(vals []interface{}, index int) []interface{} {
+	out := make([]interface{}, 0, len(vals)-1)
+	out = append(out, vals[:index]...)
+	return append(out, vals[index+1:]...)
+}
+
+// Used by `Check` to report mismatches.
+func sameValues(one, other []interface{}) bool {
+	return reflect.DeepEqual(normalize(one), normalize(other))
+}
@@ -0,0 +1,111 @@
+package gordtest
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/mitranim/gord"
+)
+
+// Number of random operation sequences run by `TestRandom`.
+const RandomRuns = 256
+
+// Maximum length of each operation sequence run by `TestRandom`.
+const RandomOps = 256
+
+/*
+Randomized differential test. Runs `RandomRuns` random sequences of operations
+against sets created by the given function, comparing every result, and the
+resulting contents after every operation, to those of `Model`. Deterministic:
+uses fixed seeds.
+*/
+func TestRandom(t *testing.T, newSet func() gord.OrdSet) {
+	for seed := int64(0); seed < RandomRuns; seed++ {
+		rnd := rand.New(rand.NewSource(seed))
+		ops := make([]byte, rnd.Intn(RandomOps*2))
+		_, _ = rnd.Read(ops)
+		Check(t, newSet(), new(Model), ops)
+	}
+}
+
+/*
+Interprets the bytes as a sequence of operations, applies them to both sets,
+and fails the test at the first difference in results or contents. Each
+operation takes two bytes: the first selects the method, the second selects
+one of 16 small integer values, to make collisions likely. A trailing odd byte
+is ignored. Both sets are expected to start empty.
+*/
+func Check(t testing.TB, set, ref gord.OrdSet, ops []byte) {
+	t.Helper()
+
+	var history []string
+
+	opts := checkOps
+	_, setNav := set.(gord.NavOrdSet)
+	_, refNav := ref.(gord.NavOrdSet)
+	if setNav && refNav {
+		opts = checkNavOps
+	}
+
+	for i := 0; i+1 < len(ops); i += 2 {
+		op := opts[int(ops[i])%len(opts)]
+		val := int(ops[i+1] % 16)
+
+		call := op.name
+		if op.arg {
+			call += fmt.Sprintf(`(%v)`, val)
+		} else {
+			call += `()`
+		}
+		history = append(history, call)
+
+		actual, expected := op.run(set, val), op.run(ref, val)
+		if !sameValues(expected, actual) {
+			t.Fatalf(`%v: result mismatch; expected %v, got %v; operations: %v`,
+				call, expected, actual, strings.Join(history, `, `))
+		}
+
+		if !sameValues(ref.Values(), set.Values()) {
+			t.Fatalf(`%v: contents mismatch; expected %v, got %v; operations: %v`,
+				call, ref.Values(), set.Values(), strings.Join(history, `, `))
+		}
+
+		if ref.Len() != set.Len() {
+			t.Fatalf(`%v: length mismatch; expected %v, got %v; operations: %v`,
+				call, ref.Len(), set.Len(), strings.Join(history, `, `))
+		}
+	}
+}
+
+type checkOp struct {
+	name string
+	arg  bool
+	run  func(gord.OrdSet, interface{}) []interface{}
+}
+
+var checkOps = []checkOp{
+	{`Add`, true, func(set gord.OrdSet, val interface{}) []interface{} { set.Add(val); return nil }},
+	{`Added`, true, func(set gord.OrdSet, val interface{}) []interface{} { return results(set.Added(val)) }},
+	{`Delete`, true, func(set gord.OrdSet, val interface{}) []interface{} { set.Delete(val); return nil }},
+	{`Deleted`, true, func(set gord.OrdSet, val interface{}) []interface{} { return results(set.Deleted(val)) }},
+	{`AddFirst`, true, func(set gord.OrdSet, val interface{}) []interface{} { set.AddFirst(val); return nil }},
+	{`AddedFirst`, true, func(set gord.OrdSet, val interface{}) []interface{} { return results(set.AddedFirst(val)) }},
+	{`AddLast`, true, func(set gord.OrdSet, val interface{}) []interface{} { set.AddLast(val); return nil }},
+	{`AddedLast`, true, func(set gord.OrdSet, val interface{}) []interface{} { return results(set.AddedLast(val)) }},
+	{`PoppedFirst`, false, func(set gord.OrdSet, _ interface{}) []interface{} { return results(set.PoppedFirst()) }},
+	{`PoppedLast`, false, func(set gord.OrdSet, _ interface{}) []interface{} { return results(set.PoppedLast()) }},
+	{`Has`, true, func(set gord.OrdSet, val interface{}) []interface{} { return results(set.Has(val)) }},
+	{`Len`, false, func(set gord.OrdSet, _ interface{}) []interface{} { return results(set.Len()) }},
+}
+
+// Used instead of `checkOps` when the set implements `gord.NavOrdSet`.
+var checkNavOps = append(checkOps[:len(checkOps):len(checkOps)],
+	checkOp{`First`, false, func(set gord.OrdSet, _ interface{}) []interface{} { return results(set.(gord.NavOrdSet).First()) }},
+	checkOp{`Last`, false, func(set gord.OrdSet, _ interface{}) []interface{} { return results(set.(gord.NavOrdSet).Last()) }},
+	checkOp{`Next`, true, func(set gord.OrdSet, val interface{}) []interface{} { return results(set.(gord.NavOrdSet).Next(val)) }},
+	checkOp{`Prev`, true, func(set gord.OrdSet, val interface{}) []interface{} { return results(set.(gord.NavOrdSet).Prev(val)) }},
+)
+
+func results(vals ...interface{}) []interface{} { return vals }
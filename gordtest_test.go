@@ -0,0 +1,71 @@
+package gord_test
+
+import (
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/mitranim/gord"
+	"github.com/mitranim/gord/gordtest"
+)
+
+// Runs the `gordtest` contract against every `OrdSet` in this package.
+func TestOrdSet(t *testing.T) {
+	testOrdSet(t, `LinkedSet`, func() gord.OrdSet { return new(gord.LinkedSet) })
+	testOrdSet(t, `SyncLinkedSet`, func() gord.OrdSet { return new(gord.SyncLinkedSet) })
+	testOrdSet(t, `SliceSet`, func() gord.OrdSet { return new(gord.SliceSet) })
+	testOrdSet(t, `SyncSliceSet`, func() gord.OrdSet { return new(gord.SyncSliceSet) })
+	testOrdSet(t, `CowSliceSet`, func() gord.OrdSet { return new(gord.CowSliceSet) })
+	testOrdSet(t, `LinkedIntSet`, func() gord.OrdSet { return new(gord.LinkedIntSet).OrdSet() })
+	testOrdSet(t, `CountingSet`, func() gord.OrdSet { return gord.NewCountingSet(new(gord.LinkedSet)) })
+
+	dir := t.TempDir()
+	count := 0
+	testOrdSet(t, `WalSet`, func() gord.OrdSet {
+		count++
+		set, err := gord.OpenWalSet(
+			filepath.Join(dir, strconv.Itoa(count)),
+			gord.WalOptions{Codec: intCodec{}, Sync: gord.WalSyncNever},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return set
+	})
+}
+
+func testOrdSet(t *testing.T, name string, newSet func() gord.OrdSet) {
+	t.Run(name, func(t *testing.T) {
+		gordtest.TestOrdSet(t, newSet)
+		t.Run(`Random`, func(t *testing.T) { gordtest.TestRandom(t, newSet) })
+	})
+}
+
+func FuzzSliceSet(f *testing.F) {
+	gordtest.Fuzz(f, func() gord.OrdSet { return new(gord.SliceSet) })
+}
+
+func FuzzSyncLinkedSet(f *testing.F) {
+	gordtest.Fuzz(f, func() gord.OrdSet { return new(gord.SyncLinkedSet) })
+}
+
+func FuzzSyncSliceSet(f *testing.F) {
+	gordtest.Fuzz(f, func() gord.OrdSet { return new(gord.SyncSliceSet) })
+}
+
+func FuzzCowSliceSet(f *testing.F) {
+	gordtest.Fuzz(f, func() gord.OrdSet { return new(gord.CowSliceSet) })
+}
+
+func FuzzLinkedIntSet(f *testing.F) {
+	gordtest.Fuzz(f, func() gord.OrdSet { return new(gord.LinkedIntSet).OrdSet() })
+}
+
+// The tests use small ints, which `gord.JSONCodec` would decode as floats.
+type intCodec struct{}
+
+func (intCodec) Encode(val interface{}) ([]byte, error) {
+	return strconv.AppendInt(nil, int64(val.(int)), 10), nil
+}
+
+func (intCodec) Decode(src []byte) (interface{}, error) { return strconv.Atoi(string(src)) }
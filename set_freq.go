@@ -0,0 +1,256 @@
+package gord
+
+import (
+	"container/list"
+	"fmt"
+)
+
+/*
+Set that counts how often each value is added, ordered by that frequency.
+Implemented as a doubly-linked list of buckets, one per distinct count, each
+holding a doubly-linked list of values, which makes every single-value
+operation O(1): incrementing a count moves the value to the next bucket, and
+the least frequent value is always the first value of the first bucket.
+
+Values with the same count are ordered by when they reached that count, which
+for values that have never been re-added is the insertion order. With a
+positive `Cap`, adding a new value to a full set first evicts the least
+frequent value; among equals, the one that reached its count first.
+
+Concurrency-unsafe. A zero value is ready to use.
+*/
+type FreqSet struct {
+	// Maximum number of values. Zero or negative means unlimited.
+	Cap int
+
+	// Optional callback, invoked with every value evicted due to `Cap`, and its
+	// count, after the new value has been added. Not invoked for values removed
+	// by other methods.
+	OnEvict func(val interface{}, count int)
+
+	set     map[interface{}]*list.Element
+	buckets list.List
+}
+
+var _ Set = (*FreqSet)(nil)
+
+type freqBucket struct {
+	count int
+	vals  list.List
+}
+
+type freqEntry struct {
+	val    interface{}
+	bucket *list.Element
+}
+
+type freqCount struct {
+	val   interface{}
+	count int
+}
+
+// Returns the number of distinct values.
+func (self *FreqSet) Len() int {
+	if self == nil {
+		return 0
+	}
+	return len(self.set)
+}
+
+// True if the value is present, regardless of its count.
+func (self *FreqSet) Has(val interface{}) bool {
+	return self != nil && self.set[val] != nil
+}
+
+// Returns the number of times the value has been added or touched, or 0 if
+// it's missing.
+func (self *FreqSet) Count(val interface{}) int {
+	if self == nil {
+		return 0
+	}
+	elem := self.set[val]
+	if elem == nil {
+		return 0
+	}
+	return entryBucket(elem).count
+}
+
+// Void version of `.Added`.
+func (self *FreqSet) Add(val interface{}) {
+	_ = self.Added(val)
+}
+
+// If `!set.Has(val)`, adds the value with a count of 1, evicting the least
+// frequent value if the set is at capacity, and returns `true`. If
+// `set.Has(val)`, increments its count and returns `false`.
+func (self *FreqSet) Added(val interface{}) bool {
+	if self.Touched(val) {
+		return false
+	}
+
+	var evicted []freqCount
+	for self.Cap > 0 && len(self.set) >= self.Cap {
+		evicted = append(evicted, self.evict())
+	}
+
+	self.init()
+	first := self.buckets.Front()
+	if first == nil || first.Value.(*freqBucket).count != 1 {
+		first = self.buckets.PushFront(&freqBucket{count: 1})
+	}
+	self.set[val] = first.Value.(*freqBucket).vals.PushBack(freqEntry{val, first})
+
+	if self.OnEvict != nil {
+		for _, entry := range evicted {
+			self.OnEvict(entry.val, entry.count)
+		}
+	}
+	return true
+}
+
+// Void version of `.Touched`.
+func (self *FreqSet) Touch(val interface{}) {
+	_ = self.Touched(val)
+}
+
+// If `set.Has(val)`, increments its count and returns `true`. Otherwise does
+// nothing and returns `false`. Unlike `.Added`, never adds the value.
+func (self *FreqSet) Touched(val interface{}) bool {
+	if self == nil {
+		return false
+	}
+	elem := self.set[val]
+	if elem == nil {
+		return false
+	}
+
+	bucket := elem.Value.(freqEntry).bucket
+	count := entryBucket(elem).count
+
+	next := bucket.Next()
+	if next == nil || next.Value.(*freqBucket).count != count+1 {
+		next = self.buckets.InsertAfter(&freqBucket{count: count + 1}, bucket)
+	}
+
+	self.removeElem(elem)
+	self.set[val] = next.Value.(*freqBucket).vals.PushBack(freqEntry{val, next})
+	return true
+}
+
+// Void version of `.Deleted`.
+func (self *FreqSet) Delete(val interface{}) {
+	_ = self.Deleted(val)
+}
+
+// If `set.Has(val)`, removes the value and its count and returns `true`.
+// Otherwise returns `false`.
+func (self *FreqSet) Deleted(val interface{}) bool {
+	if self == nil {
+		return false
+	}
+	elem := self.set[val]
+	if elem == nil {
+		return false
+	}
+	delete(self.set, val)
+	self.removeElem(elem)
+	return true
+}
+
+// Returns the least frequent value and its count; among equals, the one that
+// reached its count first. This is the next value to be evicted.
+func (self *FreqSet) Least() (interface{}, int, bool) {
+	if self.Len() == 0 {
+		return nil, 0, false
+	}
+	bucket := self.buckets.Front().Value.(*freqBucket)
+	return bucket.vals.Front().Value.(freqEntry).val, bucket.count, true
+}
+
+// Returns the most frequent value and its count; among equals, the one that
+// reached its count first.
+func (self *FreqSet) Most() (interface{}, int, bool) {
+	if self.Len() == 0 {
+		return nil, 0, false
+	}
+	bucket := self.buckets.Back().Value.(*freqBucket)
+	return bucket.vals.Front().Value.(freqEntry).val, bucket.count, true
+}
+
+// Removes and returns the value returned by `.Least`, and its count. Doesn't
+// invoke `OnEvict`.
+func (self *FreqSet) PoppedLeast() (interface{}, int, bool) {
+	if self.Len() == 0 {
+		return nil, 0, false
+	}
+	entry := self.evict()
+	return entry.val, entry.count, true
+}
+
+// Returns the values from most to least frequent; among equals, in order of
+// reaching their count.
+func (self *FreqSet) Values() []interface{} {
+	out := make([]interface{}, 0, self.Len())
+	if self == nil {
+		return out
+	}
+	for bucket := self.buckets.Back(); bucket != nil; bucket = bucket.Prev() {
+		out = bucketValues(out, bucket)
+	}
+	return out
+}
+
+// Returns the values from least to most frequent; among equals, in order of
+// reaching their count. This is the eviction order.
+func (self *FreqSet) ValuesAsc() []interface{} {
+	out := make([]interface{}, 0, self.Len())
+	if self == nil {
+		return out
+	}
+	for bucket := self.buckets.Front(); bucket != nil; bucket = bucket.Next() {
+		out = bucketValues(out, bucket)
+	}
+	return out
+}
+
+// Prints the values from most to least frequent, like a slice.
+func (self *FreqSet) String() string {
+	return fmt.Sprint(self.Values())
+}
+
+func (self *FreqSet) evict() freqCount {
+	bucket := self.buckets.Front().Value.(*freqBucket)
+	elem := bucket.vals.Front()
+	val := elem.Value.(freqEntry).val
+	delete(self.set, val)
+	self.removeElem(elem)
+	return freqCount{val, bucket.count}
+}
+
+// Removes the value's node from its bucket, and the bucket if it's now empty.
+// Doesn't touch the map.
+func (self *FreqSet) removeElem(elem *list.Element) {
+	bucket := elem.Value.(freqEntry).bucket
+	vals := &bucket.Value.(*freqBucket).vals
+	vals.Remove(elem)
+	if vals.Len() == 0 {
+		self.buckets.Remove(bucket)
+	}
+}
+
+func (self *FreqSet) init() {
+	if self.set == nil {
+		self.set = map[interface{}]*list.Element{}
+	}
+}
+
+func entryBucket(elem *list.Element) *freqBucket {
+	return elem.Value.(freqEntry).bucket.Value.(*freqBucket)
+}
+
+func bucketValues(out []interface{}, bucket *list.Element) []interface{} {
+	for elem := bucket.Value.(*freqBucket).vals.Front(); elem != nil; elem = elem.Next() {
+		out = append(out, elem.Value.(freqEntry).val)
+	}
+	return out
+}
@@ -2,8 +2,8 @@ package gord
 
 import (
 	"container/list"
+	"context"
 	"fmt"
-	"strings"
 )
 
 // Constructs a new `LinkedSet` from the provided values, deduplicating them.
@@ -21,10 +21,24 @@ func NewLinkedSet(vals ...interface{}) *LinkedSet {
 //
 // Concurrency-unsafe; use `SyncLinkedSet` for concurrent access.
 type LinkedSet struct {
-	set map[interface{}]*list.Element
-	ord list.List
+	debug debugState
+	set   map[interface{}]*list.Element
+	ord   list.List
+
+	// Order-insensitive fingerprint, maintained incrementally after the first
+	// call to `.Fingerprint`. See `.indexElem`.
+	hash   uint64
+	hashed bool
+
+	// Incremented by every modification; see `.Version`.
+	version uint64
 }
 
+var (
+	_ NavOrdSet      = (*LinkedSet)(nil)
+	_ StringerOrdSet = (*LinkedSet)(nil)
+)
+
 // Satisfy `Set`.
 func (self *LinkedSet) Len() int {
 	if self == nil {
@@ -49,12 +63,15 @@ func (self *LinkedSet) Add(val interface{}) {
 
 // Satisfy `Set`.
 func (self *LinkedSet) Added(val interface{}) bool {
+	if debugMode {
+		defer self.debugWrite()()
+	}
 	if self.Has(val) {
 		return false
 	}
 
 	self.init()
-	self.set[val] = self.ord.PushBack(val)
+	self.indexElem(self.ord.PushBack(val))
 	return true
 }
 
@@ -65,6 +82,9 @@ func (self *LinkedSet) Delete(val interface{}) {
 
 // Satisfy `Set`.
 func (self *LinkedSet) Deleted(val interface{}) bool {
+	if debugMode {
+		defer self.debugWrite()()
+	}
 	elem := self.set[val]
 	if elem == nil {
 		return false
@@ -80,15 +100,19 @@ func (self *LinkedSet) AddFirst(val interface{}) {
 
 // Satisfy `OrdSet`.
 func (self *LinkedSet) AddedFirst(val interface{}) bool {
+	if debugMode {
+		defer self.debugWrite()()
+	}
 	self.init()
 
 	elem := self.set[val]
 	if elem != nil {
 		self.ord.MoveToFront(elem)
+		self.version++
 		return false
 	}
 
-	self.set[val] = self.ord.PushFront(val)
+	self.indexElem(self.ord.PushFront(val))
 	return true
 }
 
@@ -99,28 +123,323 @@ func (self *LinkedSet) AddLast(val interface{}) {
 
 // Satisfy `OrdSet`.
 func (self *LinkedSet) AddedLast(val interface{}) bool {
+	if debugMode {
+		defer self.debugWrite()()
+	}
 	self.init()
 
 	elem := self.set[val]
 	if elem != nil {
 		self.ord.MoveToBack(elem)
+		self.version++
+		return false
+	}
+
+	self.indexElem(self.ord.PushBack(val))
+	return true
+}
+
+// Void version of `.MovedFirst`.
+func (self *LinkedSet) MoveFirst(val interface{}) {
+	_ = self.MovedFirst(val)
+}
+
+// If `set.Has(val)`, moves the value to the first position and returns `true`.
+// If `!set.Has(val)`, does nothing and returns `false`. Unlike `.AddedFirst`,
+// never adds the value.
+func (self *LinkedSet) MovedFirst(val interface{}) bool {
+	if debugMode {
+		defer self.debugWrite()()
+	}
+	elem := self.set[val]
+	if elem == nil {
+		return false
+	}
+	self.ord.MoveToFront(elem)
+	self.version++
+	return true
+}
+
+// Void version of `.MovedLast`.
+func (self *LinkedSet) MoveLast(val interface{}) {
+	_ = self.MovedLast(val)
+}
+
+// If `set.Has(val)`, moves the value to the last position and returns `true`.
+// If `!set.Has(val)`, does nothing and returns `false`. Unlike `.AddedLast`,
+// never adds the value.
+func (self *LinkedSet) MovedLast(val interface{}) bool {
+	if debugMode {
+		defer self.debugWrite()()
+	}
+	elem := self.set[val]
+	if elem == nil {
+		return false
+	}
+	self.ord.MoveToBack(elem)
+	self.version++
+	return true
+}
+
+// Void version of `.Replaced`.
+func (self *LinkedSet) Replace(prev, next interface{}) {
+	_ = self.Replaced(prev, next)
+}
+
+// If `set.Has(prev)`, puts `next` in its position, removing `prev`, and returns
+// `true`. If `next` was already present elsewhere, it's removed from its old
+// position. If `!set.Has(prev)`, does nothing and returns `false`.
+func (self *LinkedSet) Replaced(prev, next interface{}) bool {
+	if debugMode {
+		defer self.debugWrite()()
+	}
+	elem := self.set[prev]
+	if elem == nil {
 		return false
 	}
+	if prev == next {
+		return true
+	}
+
+	other := self.set[next]
+	if other != nil {
+		self.removeElem(other)
+	}
 
-	self.set[val] = self.ord.PushBack(val)
+	self.unindexVal(elem.Value)
+	elem.Value = next
+	self.indexElem(elem)
 	return true
 }
 
+// Void version of `.Swapped`.
+func (self *LinkedSet) Swap(one, other interface{}) {
+	_ = self.Swapped(one, other)
+}
+
+// If both values are in the set, swaps their positions and returns `true`.
+// Otherwise does nothing and returns `false`. Relinks the list nodes without
+// touching the map.
+func (self *LinkedSet) Swapped(one, other interface{}) bool {
+	if debugMode {
+		defer self.debugWrite()()
+	}
+	elemOne, elemOther := self.set[one], self.set[other]
+	if elemOne == nil || elemOther == nil {
+		return false
+	}
+	self.swapElems(elemOne, elemOther)
+	return true
+}
+
+// Void version of `.MovedTo`.
+func (self *LinkedSet) MoveTo(val interface{}, index int) {
+	_ = self.MovedTo(val, index)
+}
+
+// If `set.Has(val)` and `0 <= index < set.Len()`, moves the value so that it
+// ends up at the given index, shifting the values in between, and returns
+// `true`. Otherwise does nothing and returns `false`. Relinks the list nodes
+// without touching the map; walks from the nearest end to find the target.
+func (self *LinkedSet) MovedTo(val interface{}, index int) bool {
+	if debugMode {
+		defer self.debugWrite()()
+	}
+	elem := self.set[val]
+	if elem == nil || index < 0 || index >= self.Len() {
+		return false
+	}
+
+	mark, markAfter := self.elemAtFrom(elem, index)
+	if mark == elem {
+		return true
+	}
+	if markAfter {
+		self.ord.MoveAfter(elem, mark)
+	} else {
+		self.ord.MoveBefore(elem, mark)
+	}
+	self.version++
+	return true
+}
+
+// Moves the first `count` values to the end, preserving their order. Negative
+// counts move the last values to the start. Counts beyond the length wrap
+// around. Relinks at most half of the list nodes, without touching the map.
+func (self *LinkedSet) Rotate(count int) {
+	if debugMode {
+		defer self.debugWrite()()
+	}
+	size := self.Len()
+	if size < 2 {
+		return
+	}
+
+	count %= size
+	if count < 0 {
+		count += size
+	}
+	if count == 0 {
+		return
+	}
+	self.version++
+
+	if count <= size/2 {
+		for ; count > 0; count-- {
+			self.ord.MoveToBack(self.ord.Front())
+		}
+	} else {
+		for count = size - count; count > 0; count-- {
+			self.ord.MoveToFront(self.ord.Back())
+		}
+	}
+}
+
 // Satisfy `OrdSet`.
 func (self *LinkedSet) PoppedFirst() (interface{}, bool) {
+	if debugMode {
+		defer self.debugWrite()()
+	}
 	return self.poppedElem(self.ord.Front())
 }
 
 // Satisfy `OrdSet`.
 func (self *LinkedSet) PoppedLast() (interface{}, bool) {
+	if debugMode {
+		defer self.debugWrite()()
+	}
 	return self.poppedElem(self.ord.Back())
 }
 
+// Removes up to `count` first values and returns them in their original
+// order. Returns `nil` if the set is empty or `count <= 0`.
+func (self *LinkedSet) PoppedFirstN(count int) []interface{} {
+	if debugMode {
+		defer self.debugWrite()()
+	}
+	count = clampCount(count, self.Len())
+	if count == 0 {
+		return nil
+	}
+
+	out := make([]interface{}, count)
+	for i := range out {
+		out[i], _ = self.poppedElem(self.ord.Front())
+	}
+	return out
+}
+
+// Removes up to `count` last values and returns them in their original order,
+// which is the reverse of the order of popping them one by one. Returns `nil`
+// if the set is empty or `count <= 0`.
+func (self *LinkedSet) PoppedLastN(count int) []interface{} {
+	if debugMode {
+		defer self.debugWrite()()
+	}
+	count = clampCount(count, self.Len())
+	if count == 0 {
+		return nil
+	}
+
+	out := make([]interface{}, count)
+	for i := range out {
+		out[count-1-i], _ = self.poppedElem(self.ord.Back())
+	}
+	return out
+}
+
+// Removes every value and returns them in their original order. The set
+// remains usable afterwards. Returns `nil` if the set is empty.
+func (self *LinkedSet) Drain() []interface{} {
+	if debugMode {
+		defer self.debugWrite()()
+	}
+	if self.Len() == 0 {
+		return nil
+	}
+	out := self.Values()
+	self.clear()
+	return out
+}
+
+// Satisfy `OrdSet`.
+func (self *LinkedSet) First() (interface{}, bool) {
+	if self == nil {
+		return nil, false
+	}
+	return elemValue(self.ord.Front())
+}
+
+// Satisfy `OrdSet`.
+func (self *LinkedSet) Last() (interface{}, bool) {
+	if self == nil {
+		return nil, false
+	}
+	return elemValue(self.ord.Back())
+}
+
+// Satisfy `OrdSet`. Constant-time.
+func (self *LinkedSet) Next(val interface{}) (interface{}, bool) {
+	if self == nil {
+		return nil, false
+	}
+	elem := self.set[val]
+	if elem == nil {
+		return nil, false
+	}
+	return elemValue(elem.Next())
+}
+
+// Satisfy `OrdSet`. Constant-time.
+func (self *LinkedSet) Prev(val interface{}) (interface{}, bool) {
+	if self == nil {
+		return nil, false
+	}
+	elem := self.set[val]
+	if elem == nil {
+		return nil, false
+	}
+	return elemValue(elem.Prev())
+}
+
+// Returns a new set with the values between indexes `start` (inclusive) and
+// `end` (exclusive), in the same order. Unlike slicing, out-of-range indexes
+// are clamped rather than causing a panic, and `start >= end` produces an
+// empty set. Walks the list from the nearest end, without allocating a slice
+// of every value.
+func (self *LinkedSet) Slice(start, end int) *LinkedSet {
+	var out LinkedSet
+	self.sliceInto(&out, start, end)
+	return &out
+}
+
+// Returns a new set with the values from `first` to `last`, both inclusive, in
+// the same order. If either value is missing, or if `last` precedes `first`,
+// the resulting set is empty.
+func (self *LinkedSet) Between(first, last interface{}) *LinkedSet {
+	var out LinkedSet
+	self.betweenInto(&out, first, last)
+	return &out
+}
+
+// Returns a new set with up to `count` first values. Shortcut for
+// `.Slice(0, count)`.
+func (self *LinkedSet) Take(count int) *LinkedSet {
+	return self.Slice(0, count)
+}
+
+// Returns a new set with up to `count` last values, in the same order. Shortcut
+// for `.Slice(set.Len()-count, set.Len())`.
+func (self *LinkedSet) TakeLast(count int) *LinkedSet {
+	return self.Slice(self.Len()-count, self.Len())
+}
+
+// Returns a new set without the first `count` values. Shortcut for
+// `.Slice(count, set.Len())`.
+func (self *LinkedSet) Drop(count int) *LinkedSet {
+	return self.Slice(count, self.Len())
+}
+
 // Satisfy `OrdSet`. The slice is allocated every time and is OK to mutate.
 func (self *LinkedSet) Values() []interface{} {
 	if self == nil {
@@ -128,42 +447,67 @@ func (self *LinkedSet) Values() []interface{} {
 	}
 
 	out := make([]interface{}, 0, self.ord.Len())
-	self.each(func(_ int, val interface{}) {
+	self.Each(func(_ int, val interface{}) {
 		out = append(out, val)
 	})
 	return out
 }
 
-// Satisfy `StringerOrdSet`.
-func (self *LinkedSet) String() string {
+// Calls the function for every value in order, with its index, without
+// allocating. The function must not modify the set; if it does, this panics
+// with `*ConcurrentModificationError` as soon as the function returns. To
+// modify the set while walking it, use `.Cursor`.
+func (self *LinkedSet) Each(fun func(int, interface{})) {
 	if self == nil {
-		return `[]`
+		return
+	}
+	version := self.version
+	i := 0
+	for elem := self.ord.Front(); elem != nil; elem = elem.Next() {
+		fun(i, elem.Value)
+		self.checkVersion(version, i, elem.Value)
+		i++
 	}
-	// Inefficient but simple.
-	return fmt.Sprint(self.Values())
 }
 
-// Satisfy `StringerOrdSet`.
-func (self *LinkedSet) GoString() string {
+// Same as `.Each`, but stops at the first error and returns it. Also panics if
+// the function modifies the set.
+func (self *LinkedSet) EachErr(fun func(int, interface{}) error) error {
 	if self == nil {
-		return `(*LinkedSet)(nil)`
+		return nil
 	}
+	return eachErr(self.eachWhile, fun)
+}
 
-	var buf strings.Builder
-	buf.WriteString(`NewLinkedSet`)
-	self.writeGoString(&buf)
-	return buf.String()
+// Same as `.EachErr`, but also checks the context before every value, and
+// returns `ctx.Err()` once it's done.
+func (self *LinkedSet) EachCtx(ctx context.Context, fun func(int, interface{}) error) error {
+	return self.EachErr(eachCtx(ctx, fun))
 }
 
-func (self *LinkedSet) writeGoString(buf *strings.Builder) {
-	buf.WriteString(`(`)
-	self.each(func(i int, val interface{}) {
-		if i > 0 {
-			buf.WriteString(`, `)
-		}
-		fmt.Fprintf(buf, "%#v", val)
-	})
-	buf.WriteString(`)`)
+/*
+Returns a counter that increases with every modification of the set: adding,
+deleting, replacing or reordering values. Operations that neither add, delete
+nor move anything, such as adding a present value, leave it unchanged; moving a
+value to its current position may still increment it. Comparing versions is a
+cheap way to detect whether the set has changed since an earlier call. Iterators
+such as `.Each` use it to detect modification during iteration.
+*/
+func (self *LinkedSet) Version() uint64 {
+	if self == nil {
+		return 0
+	}
+	return self.version
+}
+
+// Satisfy `StringerOrdSet`. Same as formatting with `%v`; see `.Format`.
+func (self *LinkedSet) String() string {
+	return fmt.Sprint(self)
+}
+
+// Satisfy `StringerOrdSet`. Same as formatting with `%#v`; see `.Format`.
+func (self *LinkedSet) GoString() string {
+	return fmt.Sprintf(`%#v`, self)
 }
 
 func (self *LinkedSet) init() {
@@ -172,9 +516,59 @@ func (self *LinkedSet) init() {
 	}
 }
 
+func (self *LinkedSet) clear() {
+	self.set = nil
+	self.ord.Init()
+	self.hash = 0
+	self.version++
+}
+
 func (self *LinkedSet) removeElem(elem *list.Element) {
 	self.ord.Remove(elem)
-	delete(self.set, elem.Value)
+	self.unindexVal(elem.Value)
+}
+
+// Every insertion into the map must go through this method, and every deletion
+// through `.unindexVal`, to keep the incremental fingerprint up to date. Both
+// also increment the version. Methods that only reorder the list must
+// increment it themselves.
+func (self *LinkedSet) indexElem(elem *list.Element) {
+	self.set[elem.Value] = elem
+	self.version++
+	if self.hashed {
+		self.hash += hashVal(elem.Value)
+	}
+}
+
+func (self *LinkedSet) unindexVal(val interface{}) {
+	delete(self.set, val)
+	self.version++
+	if self.hashed {
+		self.hash -= hashVal(val)
+	}
+}
+
+func (self *LinkedSet) swapElems(one, other *list.Element) {
+	if one == other {
+		return
+	}
+	self.version++
+	if one.Next() == other {
+		self.ord.MoveAfter(one, other)
+		return
+	}
+	if other.Next() == one {
+		self.ord.MoveAfter(other, one)
+		return
+	}
+
+	prev := one.Prev()
+	self.ord.MoveAfter(one, other)
+	if prev == nil {
+		self.ord.MoveToFront(other)
+	} else {
+		self.ord.MoveAfter(other, prev)
+	}
 }
 
 func (self *LinkedSet) poppedElem(elem *list.Element) (interface{}, bool) {
@@ -185,10 +579,110 @@ func (self *LinkedSet) poppedElem(elem *list.Element) (interface{}, bool) {
 	return elem.Value, true
 }
 
-func (self LinkedSet) each(fun func(i int, val interface{})) {
+func (self *LinkedSet) sliceInto(out *LinkedSet, start, end int) {
+	start, end = clampRange(start, end, self.Len())
+	if start == end {
+		return
+	}
+
+	elem := self.elemAt(start)
+	for i := start; i < end; i++ {
+		out.Add(elem.Value)
+		elem = elem.Next()
+	}
+}
+
+func (self *LinkedSet) betweenInto(out *LinkedSet, first, last interface{}) {
+	if self == nil {
+		return
+	}
+
+	head, tail := self.set[first], self.set[last]
+	if head == nil || tail == nil {
+		return
+	}
+
+	// Verify the order before copying anything.
+	elem := head
+	for elem != nil && elem != tail {
+		elem = elem.Next()
+	}
+	if elem == nil {
+		return
+	}
+
+	for elem := head; ; elem = elem.Next() {
+		out.Add(elem.Value)
+		if elem == tail {
+			return
+		}
+	}
+}
+
+// Same as `.elemAt`, but also reports whether the element at the index comes
+// after the given element, which must be present.
+func (self *LinkedSet) elemAtFrom(from *list.Element, index int) (*list.Element, bool) {
+	if index < self.ord.Len()/2 {
+		after := false
+		elem := self.ord.Front()
+		for ; index > 0; index-- {
+			if elem == from {
+				after = true
+			}
+			elem = elem.Next()
+		}
+		return elem, after
+	}
+
+	after := true
+	elem := self.ord.Back()
+	for index = self.ord.Len() - 1 - index; index > 0; index-- {
+		if elem == from {
+			after = false
+		}
+		elem = elem.Prev()
+	}
+	return elem, after
+}
+
+// Assumes that the index is within bounds. Walks from the nearest end.
+func (self *LinkedSet) elemAt(index int) *list.Element {
+	if index < self.ord.Len()/2 {
+		elem := self.ord.Front()
+		for ; index > 0; index-- {
+			elem = elem.Next()
+		}
+		return elem
+	}
+
+	elem := self.ord.Back()
+	for index = self.ord.Len() - 1 - index; index > 0; index-- {
+		elem = elem.Prev()
+	}
+	return elem
+}
+
+func elemValue(elem *list.Element) (interface{}, bool) {
+	if elem == nil {
+		return nil, false
+	}
+	return elem.Value, true
+}
+
+func (self *LinkedSet) eachWhile(fun func(interface{}) bool) {
+	version := self.version
 	i := 0
 	for elem := self.ord.Front(); elem != nil; elem = elem.Next() {
-		fun(i, elem.Value)
+		if !fun(elem.Value) {
+			return
+		}
+		self.checkVersion(version, i, elem.Value)
 		i++
 	}
 }
+
+func (self *LinkedSet) checkVersion(version uint64, index int, val interface{}) {
+	if self.version != version {
+		panic(&ConcurrentModificationError{Index: index, Value: val})
+	}
+}
@@ -1,8 +1,8 @@
 package gord
 
 import (
-	"strings"
-	"sync"
+	"context"
+	"fmt"
 )
 
 // Constructs a new `SyncLinkedSet` from the provided values, deduplicating them.
@@ -18,10 +18,15 @@ func NewSyncLinkedSet(vals ...interface{}) *SyncLinkedSet {
 // `OrdSet` interface. A zero value is ready to use, but should never be
 // copied. Uses a mutex.
 type SyncLinkedSet struct {
-	lock sync.Mutex
+	lock statMutex
 	set  LinkedSet
 }
 
+var (
+	_ NavOrdSet      = (*SyncLinkedSet)(nil)
+	_ StringerOrdSet = (*SyncLinkedSet)(nil)
+)
+
 // Concurrency-safe version of `LinkedSet.Len`.
 func (self *SyncLinkedSet) Len() int {
 	if self == nil {
@@ -98,6 +103,83 @@ func (self *SyncLinkedSet) AddedLast(val interface{}) bool {
 	return self.set.AddedLast(val)
 }
 
+// Concurrency-safe version of `LinkedSet.MoveFirst`.
+func (self *SyncLinkedSet) MoveFirst(val interface{}) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.set.MoveFirst(val)
+}
+
+// Concurrency-safe version of `LinkedSet.MovedFirst`.
+func (self *SyncLinkedSet) MovedFirst(val interface{}) bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.set.MovedFirst(val)
+}
+
+// Concurrency-safe version of `LinkedSet.MoveLast`.
+func (self *SyncLinkedSet) MoveLast(val interface{}) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.set.MoveLast(val)
+}
+
+// Concurrency-safe version of `LinkedSet.MovedLast`.
+func (self *SyncLinkedSet) MovedLast(val interface{}) bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.set.MovedLast(val)
+}
+
+// Concurrency-safe version of `LinkedSet.Replace`.
+func (self *SyncLinkedSet) Replace(prev, next interface{}) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.set.Replace(prev, next)
+}
+
+// Concurrency-safe version of `LinkedSet.Replaced`.
+func (self *SyncLinkedSet) Replaced(prev, next interface{}) bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.set.Replaced(prev, next)
+}
+
+// Concurrency-safe version of `LinkedSet.Swap`.
+func (self *SyncLinkedSet) Swap(one, other interface{}) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.set.Swap(one, other)
+}
+
+// Concurrency-safe version of `LinkedSet.Swapped`.
+func (self *SyncLinkedSet) Swapped(one, other interface{}) bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.set.Swapped(one, other)
+}
+
+// Concurrency-safe version of `LinkedSet.MoveTo`.
+func (self *SyncLinkedSet) MoveTo(val interface{}, index int) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.set.MoveTo(val, index)
+}
+
+// Concurrency-safe version of `LinkedSet.MovedTo`.
+func (self *SyncLinkedSet) MovedTo(val interface{}, index int) bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.set.MovedTo(val, index)
+}
+
+// Concurrency-safe version of `LinkedSet.Rotate`.
+func (self *SyncLinkedSet) Rotate(count int) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.set.Rotate(count)
+}
+
 // Concurrency-safe version of `LinkedSet.PoppedFirst`.
 func (self *SyncLinkedSet) PoppedFirst() (interface{}, bool) {
 	self.lock.Lock()
@@ -112,6 +194,124 @@ func (self *SyncLinkedSet) PoppedLast() (interface{}, bool) {
 	return self.set.PoppedLast()
 }
 
+// Concurrency-safe version of `LinkedSet.PoppedFirstN`. Takes the lock once.
+func (self *SyncLinkedSet) PoppedFirstN(count int) []interface{} {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.set.PoppedFirstN(count)
+}
+
+// Concurrency-safe version of `LinkedSet.PoppedLastN`. Takes the lock once.
+func (self *SyncLinkedSet) PoppedLastN(count int) []interface{} {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.set.PoppedLastN(count)
+}
+
+// Concurrency-safe version of `LinkedSet.Drain`. Empties the set atomically.
+func (self *SyncLinkedSet) Drain() []interface{} {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.set.Drain()
+}
+
+// Concurrency-safe version of `LinkedSet.First`.
+func (self *SyncLinkedSet) First() (interface{}, bool) {
+	if self == nil {
+		return nil, false
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.set.First()
+}
+
+// Concurrency-safe version of `LinkedSet.Last`.
+func (self *SyncLinkedSet) Last() (interface{}, bool) {
+	if self == nil {
+		return nil, false
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.set.Last()
+}
+
+// Concurrency-safe version of `LinkedSet.Next`.
+func (self *SyncLinkedSet) Next(val interface{}) (interface{}, bool) {
+	if self == nil {
+		return nil, false
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.set.Next(val)
+}
+
+// Concurrency-safe version of `LinkedSet.Prev`.
+func (self *SyncLinkedSet) Prev(val interface{}) (interface{}, bool) {
+	if self == nil {
+		return nil, false
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.set.Prev(val)
+}
+
+// Concurrency-safe version of `LinkedSet.Slice`. The result is a consistent
+// snapshot.
+func (self *SyncLinkedSet) Slice(start, end int) *SyncLinkedSet {
+	var out SyncLinkedSet
+	if self == nil {
+		return &out
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.set.sliceInto(&out.set, start, end)
+	return &out
+}
+
+// Concurrency-safe version of `LinkedSet.Between`. The result is a consistent
+// snapshot.
+func (self *SyncLinkedSet) Between(first, last interface{}) *SyncLinkedSet {
+	var out SyncLinkedSet
+	if self == nil {
+		return &out
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.set.betweenInto(&out.set, first, last)
+	return &out
+}
+
+// Concurrency-safe version of `LinkedSet.Take`.
+func (self *SyncLinkedSet) Take(count int) *SyncLinkedSet {
+	return self.Slice(0, count)
+}
+
+// Concurrency-safe version of `LinkedSet.TakeLast`. Unlike
+// `.Slice(set.Len()-count, set.Len())`, reads the length under the same lock.
+func (self *SyncLinkedSet) TakeLast(count int) *SyncLinkedSet {
+	var out SyncLinkedSet
+	if self == nil {
+		return &out
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.set.sliceInto(&out.set, self.set.Len()-count, self.set.Len())
+	return &out
+}
+
+// Concurrency-safe version of `LinkedSet.Drop`. Unlike `.Slice(count,
+// set.Len())`, reads the length under the same lock.
+func (self *SyncLinkedSet) Drop(count int) *SyncLinkedSet {
+	var out SyncLinkedSet
+	if self == nil {
+		return &out
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.set.sliceInto(&out.set, count, self.set.Len())
+	return &out
+}
+
 // Concurrency-safe version of `LinkedSet.Values`.
 func (self *SyncLinkedSet) Values() []interface{} {
 	if self == nil {
@@ -122,27 +322,84 @@ func (self *SyncLinkedSet) Values() []interface{} {
 	return self.set.Values()
 }
 
-// Concurrency-safe version of `LinkedSet.String`.
-func (self *SyncLinkedSet) String() string {
+// Concurrency-safe version of `LinkedSet.Each`. Holds the lock throughout,
+// so the function must not use the set. To iterate without blocking other
+// users, use `.Snapshot`.
+func (self *SyncLinkedSet) Each(fun func(int, interface{})) {
 	if self == nil {
-		return `[]`
+		return
 	}
 	self.lock.Lock()
 	defer self.lock.Unlock()
-	return self.set.String()
+	self.set.Each(fun)
 }
 
-// Concurrency-safe version of `LinkedSet.GoString`.
-func (self *SyncLinkedSet) GoString() string {
+// Concurrency-safe version of `LinkedSet.EachErr`. Holds the lock
+// throughout, like `.Each`.
+func (self *SyncLinkedSet) EachErr(fun func(int, interface{}) error) error {
 	if self == nil {
-		return `(*SyncLinkedSet)(nil)`
+		return nil
 	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.set.EachErr(fun)
+}
 
+// Concurrency-safe version of `LinkedSet.EachCtx`. Holds the lock
+// throughout, like `.Each`.
+func (self *SyncLinkedSet) EachCtx(ctx context.Context, fun func(int, interface{}) error) error {
+	return self.EachErr(eachCtx(ctx, fun))
+}
+
+/*
+Returns a copy of the values as a `SliceSet`, taken under the lock. Iterating
+over the copy, for example via `.EachCtx`, doesn't hold the lock, so slow
+callbacks don't block other users, and may use this set. Costs one slice
+allocation, like `.Values`.
+*/
+func (self *SyncLinkedSet) Snapshot() SliceSet {
+	return self.Values()
+}
+
+// Concurrency-safe version of `LinkedSet.Version`.
+func (self *SyncLinkedSet) Version() uint64 {
+	if self == nil {
+		return 0
+	}
 	self.lock.Lock()
 	defer self.lock.Unlock()
+	return self.set.Version()
+}
+
+// Concurrency-safe version of `LinkedSet.String`.
+func (self *SyncLinkedSet) String() string {
+	return fmt.Sprint(self)
+}
+
+// Concurrency-safe version of `LinkedSet.GoString`.
+func (self *SyncLinkedSet) GoString() string {
+	return fmt.Sprintf(`%#v`, self)
+}
 
-	var buf strings.Builder
-	buf.WriteString(`NewSyncLinkedSet`)
-	self.set.writeGoString(&buf)
-	return buf.String()
+// Runs the function against the inner `LinkedSet` under a single lock
+// acquisition, making multi-step sequences such as check-then-act atomic. The
+// function must not retain the `*LinkedSet` after returning, and must not call
+// methods of the same `SyncLinkedSet`, which would deadlock.
+func (self *SyncLinkedSet) Update(fun func(*LinkedSet)) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	fun(&self.set)
+}
+
+// Same as `.Update`, but for read-only access. The function must not mutate
+// the set. Currently uses the same exclusive lock as `.Update`; the separate
+// method documents intent and leaves room for a read-write lock.
+func (self *SyncLinkedSet) View(fun func(*LinkedSet)) {
+	if self == nil {
+		fun(nil)
+		return
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	fun(&self.set)
 }
@@ -0,0 +1,388 @@
+package gord
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+/*
+Set where each value has a float score, ordered by score, similar to sorted
+sets in Redis. Values with the same score are ordered by insertion; changing a
+value's score doesn't change its place among equals. Implemented as a map plus
+a skip list whose links record how many nodes they span, which makes adding,
+deleting, rescoring and ranking take O(log n), and range queries take
+O(log n + m) for m results.
+
+Satisfies `Set`: `.Add` and `.Added` use a score of 0 for new values, and don't
+change the score of existing values. Scores must not be NaN; methods that take
+a score panic on NaN, because it would break the ordering.
+
+Concurrency-unsafe. A zero value is ready to use.
+*/
+type ScoredSet struct {
+	set   map[interface{}]*scoredNode
+	head  scoredNode
+	tail  *scoredNode
+	level int
+	seq   uint64
+	seed  uint64
+}
+
+var _ Set = (*ScoredSet)(nil)
+
+// Maximum height of the skip list. With a branching factor of 4, this is
+// enough for far more values than can fit in memory.
+const scoredMaxLevel = 32
+
+type scoredNode struct {
+	val   interface{}
+	score float64
+	seq   uint64
+	back  *scoredNode
+	next  []scoredLink
+}
+
+// Link to the next node at a given level, with the number of level-0 links it
+// skips over. Links to nil span the rest of the list.
+type scoredLink struct {
+	node *scoredNode
+	span int
+}
+
+// Satisfy `Set`.
+func (self *ScoredSet) Len() int {
+	if self == nil {
+		return 0
+	}
+	return len(self.set)
+}
+
+// Satisfy `Set`.
+func (self *ScoredSet) Has(val interface{}) bool {
+	return self != nil && self.set[val] != nil
+}
+
+// Satisfy `Set`.
+func (self *ScoredSet) Add(val interface{}) {
+	_ = self.Added(val)
+}
+
+// Satisfy `Set`. If `!set.Has(val)`, adds the value with a score of 0 and
+// returns `true`. Otherwise does nothing and returns `false`.
+func (self *ScoredSet) Added(val interface{}) bool {
+	if self.Has(val) {
+		return false
+	}
+	self.insert(val, 0)
+	return true
+}
+
+// Satisfy `Set`.
+func (self *ScoredSet) Delete(val interface{}) {
+	_ = self.Deleted(val)
+}
+
+// Satisfy `Set`.
+func (self *ScoredSet) Deleted(val interface{}) bool {
+	if self == nil {
+		return false
+	}
+	node := self.set[val]
+	if node == nil {
+		return false
+	}
+	self.remove(node)
+	return true
+}
+
+// Void version of `.AddedWithScore`.
+func (self *ScoredSet) AddWithScore(val interface{}, score float64) {
+	_ = self.AddedWithScore(val, score)
+}
+
+// Sets the value's score, adding the value if it's missing. Returns `true` if
+// the value was added, and `false` if it was already present.
+func (self *ScoredSet) AddedWithScore(val interface{}, score float64) bool {
+	requireScore(score)
+
+	node := self.set[val]
+	if node == nil {
+		self.insert(val, score)
+		return true
+	}
+	self.rescore(node, score)
+	return false
+}
+
+// Adds the delta to the value's score and returns the new score. If the value
+// is missing, adds it with the delta as its score.
+func (self *ScoredSet) IncrScore(val interface{}, delta float64) float64 {
+	requireScore(delta)
+
+	node := self.set[val]
+	if node == nil {
+		self.insert(val, delta)
+		return delta
+	}
+
+	score := node.score + delta
+	requireScore(score)
+	self.rescore(node, score)
+	return score
+}
+
+// If the value is present, returns its score and `true`. Otherwise returns
+// `(0, false)`.
+func (self *ScoredSet) Score(val interface{}) (float64, bool) {
+	if self == nil {
+		return 0, false
+	}
+	node := self.set[val]
+	if node == nil {
+		return 0, false
+	}
+	return node.score, true
+}
+
+// If the value is present, returns its 0-based position in ascending order and
+// `true`. Otherwise returns `(-1, false)`.
+func (self *ScoredSet) Rank(val interface{}) (int, bool) {
+	if self == nil {
+		return -1, false
+	}
+	node := self.set[val]
+	if node == nil {
+		return -1, false
+	}
+
+	rank := 0
+	elem := &self.head
+	for i := self.level - 1; i >= 0; i-- {
+		for next := elem.next[i]; next.node != nil && !node.less(next.node); next = elem.next[i] {
+			rank += next.span
+			elem = next.node
+		}
+		if elem == node {
+			return rank - 1, true
+		}
+	}
+	return -1, false
+}
+
+// Returns the values between ranks `start` (inclusive) and `end` (exclusive)
+// in ascending order. Like `LinkedSet.Slice`, out-of-range indexes are clamped,
+// and `start >= end` produces an empty slice.
+func (self *ScoredSet) RangeByRank(start, end int) []interface{} {
+	start, end = clampRange(start, end, self.Len())
+	out := make([]interface{}, 0, end-start)
+	if start == end {
+		return out
+	}
+
+	for node := self.nodeAt(start); len(out) < end-start; node = node.next[0].node {
+		out = append(out, node.val)
+	}
+	return out
+}
+
+// Returns the values with scores between `min` and `max`, both inclusive, in
+// ascending order. If `min > max`, the result is empty.
+func (self *ScoredSet) RangeByScore(min, max float64) []interface{} {
+	out := []interface{}{}
+	if self.Len() == 0 || !(min <= max) {
+		return out
+	}
+
+	elem := &self.head
+	for i := self.level - 1; i >= 0; i-- {
+		for next := elem.next[i].node; next != nil && next.score < min; next = elem.next[i].node {
+			elem = next
+		}
+	}
+
+	for node := elem.next[0].node; node != nil && node.score <= max; node = node.next[0].node {
+		out = append(out, node.val)
+	}
+	return out
+}
+
+// If the set is empty, returns `(nil, 0, false)`. Otherwise removes the value
+// with the lowest score, and returns it with its score and `true`.
+func (self *ScoredSet) PoppedMin() (interface{}, float64, bool) {
+	if self.Len() == 0 {
+		return nil, 0, false
+	}
+	return self.popped(self.head.next[0].node)
+}
+
+// If the set is empty, returns `(nil, 0, false)`. Otherwise removes the value
+// with the highest score, and returns it with its score and `true`. Among
+// equals, removes the last inserted.
+func (self *ScoredSet) PoppedMax() (interface{}, float64, bool) {
+	if self.Len() == 0 {
+		return nil, 0, false
+	}
+	return self.popped(self.tail)
+}
+
+// Returns the values in ascending order of scores.
+func (self *ScoredSet) Values() []interface{} {
+	out := make([]interface{}, 0, self.Len())
+	if self.Len() == 0 {
+		return out
+	}
+	for node := self.head.next[0].node; node != nil; node = node.next[0].node {
+		out = append(out, node.val)
+	}
+	return out
+}
+
+// Prints the values in ascending order of scores, like a slice.
+func (self *ScoredSet) String() string {
+	return fmt.Sprint(self.Values())
+}
+
+func (self *ScoredSet) popped(node *scoredNode) (interface{}, float64, bool) {
+	self.remove(node)
+	return node.val, node.score, true
+}
+
+// Reinserts the node, keeping its sequence number, and therefore its position
+// among values with the same score.
+func (self *ScoredSet) rescore(node *scoredNode, score float64) {
+	if node.score == score {
+		return
+	}
+	self.remove(node)
+	node.score = score
+	self.link(node)
+}
+
+func (self *ScoredSet) insert(val interface{}, score float64) {
+	self.init()
+	self.seq++
+	self.link(&scoredNode{val: val, score: score, seq: self.seq})
+}
+
+// Mostly follows the Redis implementation of sorted sets.
+func (self *ScoredSet) link(node *scoredNode) {
+	var update [scoredMaxLevel]*scoredNode
+	var rank [scoredMaxLevel]int
+
+	elem := &self.head
+	for i := self.level - 1; i >= 0; i-- {
+		if i < self.level-1 {
+			rank[i] = rank[i+1]
+		}
+		for next := elem.next[i]; next.node != nil && next.node.less(node); next = elem.next[i] {
+			rank[i] += next.span
+			elem = next.node
+		}
+		update[i] = elem
+	}
+
+	level := self.randomLevel()
+	for ; self.level < level; self.level++ {
+		update[self.level] = &self.head
+		self.head.next[self.level].span = len(self.set)
+	}
+
+	node.next = make([]scoredLink, level)
+	for i := 0; i < level; i++ {
+		prev := &update[i].next[i]
+		node.next[i] = scoredLink{prev.node, prev.span - (rank[0] - rank[i])}
+		*prev = scoredLink{node, rank[0] - rank[i] + 1}
+	}
+	for i := level; i < self.level; i++ {
+		update[i].next[i].span++
+	}
+
+	if update[0] != &self.head {
+		node.back = update[0]
+	} else {
+		node.back = nil
+	}
+	if next := node.next[0].node; next != nil {
+		next.back = node
+	} else {
+		self.tail = node
+	}
+	self.set[node.val] = node
+}
+
+func (self *ScoredSet) remove(node *scoredNode) {
+	elem := &self.head
+	for i := self.level - 1; i >= 0; i-- {
+		for next := elem.next[i].node; next != nil && next.less(node); next = elem.next[i].node {
+			elem = next
+		}
+
+		link := &elem.next[i]
+		if link.node == node {
+			*link = scoredLink{node.next[i].node, link.span + node.next[i].span - 1}
+		} else {
+			link.span--
+		}
+	}
+
+	if next := node.next[0].node; next != nil {
+		next.back = node.back
+	} else {
+		self.tail = node.back
+	}
+	for self.level > 1 && self.head.next[self.level-1].node == nil {
+		self.level--
+	}
+
+	node.next = nil
+	node.back = nil
+	delete(self.set, node.val)
+}
+
+// Returns the node at the 0-based rank, which must be in range.
+func (self *ScoredSet) nodeAt(index int) *scoredNode {
+	traversed := 0
+	elem := &self.head
+	for i := self.level - 1; i >= 0; i-- {
+		for next := elem.next[i]; next.node != nil && traversed+next.span <= index+1; next = elem.next[i] {
+			traversed += next.span
+			elem = next.node
+		}
+		if traversed == index+1 {
+			return elem
+		}
+	}
+	return nil
+}
+
+// Each level is present with probability 1/4, decided by pairs of random bits.
+func (self *ScoredSet) randomLevel() int {
+	self.seed++
+	level := bits.TrailingZeros64(mixHash(self.seed))/2 + 1
+	if level > scoredMaxLevel {
+		return scoredMaxLevel
+	}
+	return level
+}
+
+func (self *ScoredSet) init() {
+	if self.set == nil {
+		self.set = map[interface{}]*scoredNode{}
+		self.head.next = make([]scoredLink, scoredMaxLevel)
+		self.level = 1
+	}
+}
+
+func (self *scoredNode) less(other *scoredNode) bool {
+	if self.score != other.score {
+		return self.score < other.score
+	}
+	return self.seq < other.seq
+}
+
+func requireScore(score float64) {
+	if math.IsNaN(score) {
+		panic(fmt.Errorf(`[gord] invalid score: NaN`))
+	}
+}
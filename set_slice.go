@@ -1,8 +1,8 @@
 package gord
 
 import (
+	"context"
 	"fmt"
-	"strings"
 )
 
 // Constructs a new `SliceSet` from the provided values. Very similar to
@@ -20,11 +20,16 @@ func NewSliceSet(vals ...interface{}) *SliceSet {
 // have extreme performance degradation for large sets. Giving the exact values
 // of "small" and "large" requires more testing; might depend on hardware.
 //
-// There's no "concurrent" version of this type, mainly because it would have to
-// sacrifice elegance. It can be added on demand, but would have to be a struct
-// enclosing a slice.
+// Concurrency-unsafe; use `SyncSliceSet` or `CowSliceSet` for concurrent
+// access. They're structs enclosing a slice, since a bare slice has nowhere to
+// keep a lock.
 type SliceSet []interface{}
 
+var (
+	_ NavOrdSet      = (*SliceSet)(nil)
+	_ StringerOrdSet = (*SliceSet)(nil)
+)
+
 func (self *SliceSet) Len() int {
 	if self == nil {
 		return 0
@@ -34,15 +39,7 @@ func (self *SliceSet) Len() int {
 
 // Satisfy `Set`.
 func (self *SliceSet) Has(val interface{}) bool {
-	if self == nil {
-		return false
-	}
-	for _, value := range *self {
-		if value == val {
-			return true
-		}
-	}
-	return false
+	return self.index(val) >= 0
 }
 
 // Satisfy `Set`.
@@ -52,6 +49,9 @@ func (self *SliceSet) Add(val interface{}) {
 
 // Satisfy `Set`.
 func (self *SliceSet) Added(val interface{}) bool {
+	if debugMode {
+		defer self.debugWrite()()
+	}
 	if !self.Has(val) {
 		*self = append(*self, val)
 		return true
@@ -66,6 +66,9 @@ func (self *SliceSet) Delete(val interface{}) {
 
 // Satisfy `Set`.
 func (self *SliceSet) Deleted(val interface{}) bool {
+	if debugMode {
+		defer self.debugWrite()()
+	}
 	slice := *self
 
 	for i, value := range slice {
@@ -86,6 +89,9 @@ func (self *SliceSet) AddFirst(val interface{}) {
 
 // Satisfy `OrdSet`.
 func (self *SliceSet) AddedFirst(val interface{}) bool {
+	if debugMode {
+		defer self.debugWrite()()
+	}
 	slice := *self
 
 	for i, value := range slice {
@@ -116,6 +122,9 @@ func (self *SliceSet) AddLast(val interface{}) {
 
 // Satisfy `OrdSet`.
 func (self *SliceSet) AddedLast(val interface{}) bool {
+	if debugMode {
+		defer self.debugWrite()()
+	}
 	slice := *self
 
 	for i, value := range slice {
@@ -132,6 +141,9 @@ func (self *SliceSet) AddedLast(val interface{}) bool {
 
 // Satisfy `OrdSet`.
 func (self *SliceSet) PoppedFirst() (interface{}, bool) {
+	if debugMode {
+		defer self.debugWrite()()
+	}
 	slice := *self
 
 	if len(slice) > 0 {
@@ -146,6 +158,9 @@ func (self *SliceSet) PoppedFirst() (interface{}, bool) {
 
 // Satisfy `OrdSet`.
 func (self *SliceSet) PoppedLast() (interface{}, bool) {
+	if debugMode {
+		defer self.debugWrite()()
+	}
 	slice := *self
 
 	if len(slice) > 0 {
@@ -157,6 +172,221 @@ func (self *SliceSet) PoppedLast() (interface{}, bool) {
 	return nil, false
 }
 
+// Void version of `.Swapped`.
+func (self *SliceSet) Swap(one, other interface{}) {
+	_ = self.Swapped(one, other)
+}
+
+// If both values are in the set, swaps their positions and returns `true`.
+// Otherwise does nothing and returns `false`. Linear-time, like `.Has`.
+func (self *SliceSet) Swapped(one, other interface{}) bool {
+	if debugMode {
+		defer self.debugWrite()()
+	}
+	indexOne, indexOther := self.index(one), self.index(other)
+	if indexOne < 0 || indexOther < 0 {
+		return false
+	}
+	slice := *self
+	slice[indexOne], slice[indexOther] = slice[indexOther], slice[indexOne]
+	return true
+}
+
+// Void version of `.MovedTo`.
+func (self *SliceSet) MoveTo(val interface{}, index int) {
+	_ = self.MovedTo(val, index)
+}
+
+// If `set.Has(val)` and `0 <= index < set.Len()`, moves the value so that it
+// ends up at the given index, shifting the values in between, and returns
+// `true`. Otherwise does nothing and returns `false`. Shifts with a single
+// block copy, like `.AddedFirst` and `.AddedLast`.
+func (self *SliceSet) MovedTo(val interface{}, index int) bool {
+	if debugMode {
+		defer self.debugWrite()()
+	}
+	prev := self.index(val)
+	if prev < 0 || index < 0 || index >= len(*self) {
+		return false
+	}
+
+	slice := *self
+	stored := slice[prev]
+	if prev < index {
+		copy(slice[prev:index], slice[prev+1:index+1])
+	} else {
+		copy(slice[index+1:prev+1], slice[index:prev])
+	}
+	slice[index] = stored
+	return true
+}
+
+// Moves the first `count` values to the end, preserving their order. Negative
+// counts move the last values to the start. Counts beyond the length wrap
+// around. Buffers the shorter of the two parts, and moves the longer one with
+// a single block copy.
+func (self *SliceSet) Rotate(count int) {
+	if debugMode {
+		defer self.debugWrite()()
+	}
+	size := self.Len()
+	if size < 2 {
+		return
+	}
+
+	count %= size
+	if count < 0 {
+		count += size
+	}
+	if count == 0 {
+		return
+	}
+
+	slice := *self
+	if count <= size-count {
+		head := append([]interface{}(nil), slice[:count]...)
+		copy(slice, slice[count:])
+		copy(slice[size-count:], head)
+	} else {
+		tail := append([]interface{}(nil), slice[count:]...)
+		copy(slice[size-count:], slice[:count])
+		copy(slice, tail)
+	}
+}
+
+// Removes up to `count` first values and returns them in their original
+// order. Returns `nil` if the set is empty or `count <= 0`. Unlike repeated
+// `.PoppedFirst`, shifts the remaining values only once.
+func (self *SliceSet) PoppedFirstN(count int) []interface{} {
+	if debugMode {
+		defer self.debugWrite()()
+	}
+	count = clampCount(count, self.Len())
+	if count == 0 {
+		return nil
+	}
+
+	slice := *self
+	out := make([]interface{}, count)
+	copy(out, slice)
+	copy(slice, slice[count:])
+	*self = slice.truncate(len(slice) - count)
+	return out
+}
+
+// Removes up to `count` last values and returns them in their original order,
+// which is the reverse of the order of popping them one by one. Returns `nil`
+// if the set is empty or `count <= 0`.
+func (self *SliceSet) PoppedLastN(count int) []interface{} {
+	if debugMode {
+		defer self.debugWrite()()
+	}
+	count = clampCount(count, self.Len())
+	if count == 0 {
+		return nil
+	}
+
+	slice := *self
+	out := make([]interface{}, count)
+	copy(out, slice[len(slice)-count:])
+	*self = slice.truncate(len(slice) - count)
+	return out
+}
+
+// Removes every value and returns them in their original order. Returns `nil`
+// if the set is empty. The returned slice is the former backing array; the set
+// is reset to `nil` and doesn't share memory with the result.
+func (self *SliceSet) Drain() []interface{} {
+	if debugMode {
+		defer self.debugWrite()()
+	}
+	if self.Len() == 0 {
+		return nil
+	}
+	out := []interface{}(*self)
+	*self = nil
+	return out
+}
+
+// Satisfy `OrdSet`.
+func (self *SliceSet) First() (interface{}, bool) {
+	if self == nil || len(*self) == 0 {
+		return nil, false
+	}
+	return (*self)[0], true
+}
+
+// Satisfy `OrdSet`.
+func (self *SliceSet) Last() (interface{}, bool) {
+	if self == nil || len(*self) == 0 {
+		return nil, false
+	}
+	return (*self)[len(*self)-1], true
+}
+
+// Satisfy `OrdSet`. Linear-time, like `.Has`.
+func (self *SliceSet) Next(val interface{}) (interface{}, bool) {
+	index := self.index(val)
+	if index < 0 || index+1 >= len(*self) {
+		return nil, false
+	}
+	return (*self)[index+1], true
+}
+
+// Satisfy `OrdSet`. Linear-time, like `.Has`.
+func (self *SliceSet) Prev(val interface{}) (interface{}, bool) {
+	index := self.index(val)
+	if index <= 0 {
+		return nil, false
+	}
+	return (*self)[index-1], true
+}
+
+// Returns a new set with the values between indexes `start` (inclusive) and
+// `end` (exclusive), in the same order. Unlike slicing, out-of-range indexes
+// are clamped rather than causing a panic, and `start >= end` produces an
+// empty set. The result has its own backing array; mutating one set doesn't
+// affect the other.
+func (self *SliceSet) Slice(start, end int) *SliceSet {
+	start, end = clampRange(start, end, self.Len())
+	if start == end {
+		return new(SliceSet)
+	}
+
+	out := make(SliceSet, end-start)
+	copy(out, (*self)[start:end])
+	return &out
+}
+
+// Returns a new set with the values from `first` to `last`, both inclusive, in
+// the same order. If either value is missing, or if `last` precedes `first`,
+// the resulting set is empty.
+func (self *SliceSet) Between(first, last interface{}) *SliceSet {
+	start, end := self.index(first), self.index(last)
+	if start < 0 || end < start {
+		return new(SliceSet)
+	}
+	return self.Slice(start, end+1)
+}
+
+// Returns a new set with up to `count` first values. Shortcut for
+// `.Slice(0, count)`.
+func (self *SliceSet) Take(count int) *SliceSet {
+	return self.Slice(0, count)
+}
+
+// Returns a new set with up to `count` last values, in the same order. Shortcut
+// for `.Slice(set.Len()-count, set.Len())`.
+func (self *SliceSet) TakeLast(count int) *SliceSet {
+	return self.Slice(self.Len()-count, self.Len())
+}
+
+// Returns a new set without the first `count` values. Shortcut for
+// `.Slice(count, set.Len())`.
+func (self *SliceSet) Drop(count int) *SliceSet {
+	return self.Slice(count, self.Len())
+}
+
 // Satisfy `OrdSet`. Returns self. This is a free cast with no reallocation, and
 // any mutations of the resulting slice are reflected in the set.
 func (self *SliceSet) Values() []interface{} {
@@ -166,33 +396,71 @@ func (self *SliceSet) Values() []interface{} {
 	return []interface{}(*self)
 }
 
-// Satisfy `StringerOrdSet`.
-func (self *SliceSet) String() string {
-	return fmt.Sprint(self.Values())
+// Same as `LinkedSet.Each`, but only detects modifications that change the
+// length: a bare slice has nowhere to keep a version, so reordering values, or
+// deleting and adding as many, goes unnoticed. `SyncSliceSet` and
+// `CowSliceSet` track every modification; see `SyncSliceSet.Version`.
+func (self *SliceSet) Each(fun func(int, interface{})) {
+	if self == nil {
+		return
+	}
+	size := len(*self)
+	for i, val := range *self {
+		fun(i, val)
+		self.checkLen(size, i, val)
+	}
 }
 
-// Satisfy `StringerOrdSet`.
-func (self *SliceSet) GoString() string {
+// Same as `LinkedSet.EachErr`, with the limitation of `.Each`.
+func (self *SliceSet) EachErr(fun func(int, interface{}) error) error {
 	if self == nil {
-		return `(*SliceSet)(nil)`
+		return nil
 	}
+	return eachErr(self.eachWhile, fun)
+}
 
-	if *self == nil {
-		return `SliceSet(nil)`
-	}
+// Same as `LinkedSet.EachCtx`.
+func (self *SliceSet) EachCtx(ctx context.Context, fun func(int, interface{}) error) error {
+	return self.EachErr(eachCtx(ctx, fun))
+}
+
+// Satisfy `StringerOrdSet`. Same as formatting with `%v`; see `.Format`.
+func (self *SliceSet) String() string {
+	return fmt.Sprint(self)
+}
 
-	var buf strings.Builder
-	buf.WriteString(`SliceSet{`)
+// Satisfy `StringerOrdSet`. Same as formatting with `%#v`; see `.Format`.
+func (self *SliceSet) GoString() string {
+	return fmt.Sprintf(`%#v`, self)
+}
 
+func (self *SliceSet) eachWhile(fun func(interface{}) bool) {
+	size := len(*self)
 	for i, val := range *self {
-		if i > 0 {
-			buf.WriteString(`, `)
+		if !fun(val) {
+			return
 		}
-		fmt.Fprintf(&buf, "%#v", val)
+		self.checkLen(size, i, val)
 	}
+}
 
-	buf.WriteString(`}`)
-	return buf.String()
+func (self *SliceSet) checkLen(size int, index int, val interface{}) {
+	if len(*self) != size {
+		panic(&ConcurrentModificationError{Index: index, Value: val})
+	}
+}
+
+// Returns the index of the value, or -1 if it's missing.
+func (self *SliceSet) index(val interface{}) int {
+	if self == nil {
+		return -1
+	}
+	for i, value := range *self {
+		if value == val {
+			return i
+		}
+	}
+	return -1
 }
 
 func (self SliceSet) shiftLeft(index int) {
@@ -207,6 +475,16 @@ func (self SliceSet) init() SliceSet {
 	return self[:len(self)-1]
 }
 
+// Shortens the slice, clearing the vacated tail so that the backing array
+// doesn't keep the removed values reachable.
+func (self SliceSet) truncate(size int) SliceSet {
+	tail := self[size:]
+	for i := range tail {
+		tail[i] = nil
+	}
+	return self[:size]
+}
+
 // At the time of writing, this is a reasonable approximation of how the Go
 // runtime increases slice capacity when appending. The exact amount may vary
 // by type and Go version.
@@ -0,0 +1,418 @@
+package gord
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+)
+
+// Constructs a new `CowSliceSet` from the provided values, deduplicating them.
+func NewCowSliceSet(vals ...interface{}) *CowSliceSet {
+	var set SliceSet
+	for _, val := range vals {
+		set.Add(val)
+	}
+	return cowSliceSetOf(set)
+}
+
+/*
+Concurrency-safe, copy-on-write version of `SliceSet`. Satisfies the `OrdSet`
+interface. A zero value is ready to use, but should never be copied.
+
+Writes are serialized by a mutex. Each write that changes the set copies the
+current slice, modifies the copy, and atomically publishes it. Reads load the
+published slice without locking, and never block. The published slice is never
+modified after publishing, which allows `.Values` to return it without copying.
+
+Intended for small, read-heavy sets such as allow-lists. Every write that
+changes the set takes linear time and allocates, regardless of the size of
+the change. Writes that change nothing don't copy or publish anything.
+*/
+type CowSliceSet struct {
+	lock    statMutex
+	val     atomic.Value
+	version atomicUint64
+}
+
+var (
+	_ NavOrdSet      = (*CowSliceSet)(nil)
+	_ StringerOrdSet = (*CowSliceSet)(nil)
+)
+
+// Concurrency-safe version of `SliceSet.Len`. Doesn't lock.
+func (self *CowSliceSet) Len() int {
+	return len(self.load())
+}
+
+// Concurrency-safe version of `SliceSet.Has`. Doesn't lock.
+func (self *CowSliceSet) Has(val interface{}) bool {
+	set := self.load()
+	return set.Has(val)
+}
+
+// Concurrency-safe version of `SliceSet.Add`.
+func (self *CowSliceSet) Add(val interface{}) {
+	_ = self.Added(val)
+}
+
+// Concurrency-safe version of `SliceSet.Added`. Copies only if the value is
+// missing.
+func (self *CowSliceSet) Added(val interface{}) bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	set := self.load()
+	if set.Has(val) {
+		return false
+	}
+
+	next := make(SliceSet, len(set), len(set)+1)
+	copy(next, set)
+	self.publish(append(next, val))
+	return true
+}
+
+// Concurrency-safe version of `SliceSet.Delete`.
+func (self *CowSliceSet) Delete(val interface{}) {
+	_ = self.Deleted(val)
+}
+
+// Concurrency-safe version of `SliceSet.Deleted`. Copies only if the value is
+// present.
+func (self *CowSliceSet) Deleted(val interface{}) bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	set := self.load()
+	index := set.index(val)
+	if index < 0 {
+		return false
+	}
+
+	next := make(SliceSet, 0, len(set)-1)
+	next = append(next, set[:index]...)
+	next = append(next, set[index+1:]...)
+	self.publish(next)
+	return true
+}
+
+// Concurrency-safe version of `SliceSet.AddFirst`.
+func (self *CowSliceSet) AddFirst(val interface{}) {
+	_ = self.AddedFirst(val)
+}
+
+// Concurrency-safe version of `SliceSet.AddedFirst`. Copies only if the value
+// isn't already first.
+func (self *CowSliceSet) AddedFirst(val interface{}) (out bool) {
+	self.write(
+		func(set SliceSet) bool { return len(set) > 0 && set[0] == val },
+		func(set *SliceSet) { out = set.AddedFirst(val) },
+	)
+	return
+}
+
+// Concurrency-safe version of `SliceSet.AddLast`.
+func (self *CowSliceSet) AddLast(val interface{}) {
+	_ = self.AddedLast(val)
+}
+
+// Concurrency-safe version of `SliceSet.AddedLast`. Copies only if the value
+// isn't already last.
+func (self *CowSliceSet) AddedLast(val interface{}) (out bool) {
+	self.write(
+		func(set SliceSet) bool { return len(set) > 0 && set[len(set)-1] == val },
+		func(set *SliceSet) { out = set.AddedLast(val) },
+	)
+	return
+}
+
+// Concurrency-safe version of `SliceSet.Swap`.
+func (self *CowSliceSet) Swap(one, other interface{}) {
+	_ = self.Swapped(one, other)
+}
+
+// Concurrency-safe version of `SliceSet.Swapped`. Copies only if the values
+// are distinct and both present.
+func (self *CowSliceSet) Swapped(one, other interface{}) (out bool) {
+	self.write(
+		func(set SliceSet) bool {
+			indexOne, indexOther := set.index(one), set.index(other)
+			out = indexOne >= 0 && indexOther >= 0
+			return !out || indexOne == indexOther
+		},
+		func(set *SliceSet) { out = set.Swapped(one, other) },
+	)
+	return
+}
+
+// Concurrency-safe version of `SliceSet.MoveTo`.
+func (self *CowSliceSet) MoveTo(val interface{}, index int) {
+	_ = self.MovedTo(val, index)
+}
+
+// Concurrency-safe version of `SliceSet.MovedTo`. Copies only if the value
+// actually moves.
+func (self *CowSliceSet) MovedTo(val interface{}, index int) (out bool) {
+	self.write(
+		func(set SliceSet) bool {
+			prev := set.index(val)
+			out = prev >= 0 && index >= 0 && index < len(set)
+			return !out || prev == index
+		},
+		func(set *SliceSet) { out = set.MovedTo(val, index) },
+	)
+	return
+}
+
+// Concurrency-safe version of `SliceSet.Rotate`. Copies only if the order
+// changes.
+func (self *CowSliceSet) Rotate(count int) {
+	self.write(
+		func(set SliceSet) bool { return len(set) < 2 || count%len(set) == 0 },
+		func(set *SliceSet) { set.Rotate(count) },
+	)
+}
+
+// Concurrency-safe version of `SliceSet.PoppedFirst`. Copies only if the set
+// is non-empty.
+func (self *CowSliceSet) PoppedFirst() (val interface{}, ok bool) {
+	self.write(cowEmpty, func(set *SliceSet) { val, ok = set.PoppedFirst() })
+	return
+}
+
+// Concurrency-safe version of `SliceSet.PoppedLast`. Copies only if the set is
+// non-empty.
+func (self *CowSliceSet) PoppedLast() (val interface{}, ok bool) {
+	self.write(cowEmpty, func(set *SliceSet) { val, ok = set.PoppedLast() })
+	return
+}
+
+// Concurrency-safe version of `SliceSet.PoppedFirstN`. Takes the lock once.
+// Copies only if there's something to pop.
+func (self *CowSliceSet) PoppedFirstN(count int) (out []interface{}) {
+	self.write(
+		func(set SliceSet) bool { return clampCount(count, len(set)) == 0 },
+		func(set *SliceSet) { out = set.PoppedFirstN(count) },
+	)
+	return
+}
+
+// Concurrency-safe version of `SliceSet.PoppedLastN`. Takes the lock once.
+// Copies only if there's something to pop.
+func (self *CowSliceSet) PoppedLastN(count int) (out []interface{}) {
+	self.write(
+		func(set SliceSet) bool { return clampCount(count, len(set)) == 0 },
+		func(set *SliceSet) { out = set.PoppedLastN(count) },
+	)
+	return
+}
+
+// Concurrency-safe version of `SliceSet.Drain`. Empties the set atomically.
+// The returned slice is owned by the caller. Since concurrent readers may still
+// hold the last published snapshot, it's a copy of that snapshot.
+func (self *CowSliceSet) Drain() []interface{} {
+	set := self.drained()
+	if len(set) == 0 {
+		return nil
+	}
+	out := make([]interface{}, len(set))
+	copy(out, set)
+	return out
+}
+
+func (self *CowSliceSet) drained() SliceSet {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	set := self.load()
+	if len(set) > 0 {
+		self.publish(nil)
+	}
+	return set
+}
+
+// Concurrency-safe version of `SliceSet.First`. Doesn't lock.
+func (self *CowSliceSet) First() (interface{}, bool) {
+	set := self.load()
+	return set.First()
+}
+
+// Concurrency-safe version of `SliceSet.Last`. Doesn't lock.
+func (self *CowSliceSet) Last() (interface{}, bool) {
+	set := self.load()
+	return set.Last()
+}
+
+// Concurrency-safe version of `SliceSet.Next`. Doesn't lock.
+func (self *CowSliceSet) Next(val interface{}) (interface{}, bool) {
+	set := self.load()
+	return set.Next(val)
+}
+
+// Concurrency-safe version of `SliceSet.Prev`. Doesn't lock.
+func (self *CowSliceSet) Prev(val interface{}) (interface{}, bool) {
+	set := self.load()
+	return set.Prev(val)
+}
+
+// Concurrency-safe version of `SliceSet.Slice`. Doesn't lock. The result is a
+// consistent snapshot.
+func (self *CowSliceSet) Slice(start, end int) *CowSliceSet {
+	set := self.load()
+	return cowSliceSetOf(*set.Slice(start, end))
+}
+
+// Concurrency-safe version of `SliceSet.Between`. Doesn't lock. The result is a
+// consistent snapshot.
+func (self *CowSliceSet) Between(first, last interface{}) *CowSliceSet {
+	set := self.load()
+	return cowSliceSetOf(*set.Between(first, last))
+}
+
+// Concurrency-safe version of `SliceSet.Take`. Doesn't lock.
+func (self *CowSliceSet) Take(count int) *CowSliceSet {
+	return self.Slice(0, count)
+}
+
+// Concurrency-safe version of `SliceSet.TakeLast`. Doesn't lock.
+func (self *CowSliceSet) TakeLast(count int) *CowSliceSet {
+	set := self.load()
+	return cowSliceSetOf(*set.TakeLast(count))
+}
+
+// Concurrency-safe version of `SliceSet.Drop`. Doesn't lock.
+func (self *CowSliceSet) Drop(count int) *CowSliceSet {
+	set := self.load()
+	return cowSliceSetOf(*set.Drop(count))
+}
+
+// Satisfy `OrdSet`. Doesn't lock. Returns the currently published slice without
+// copying. The slice is shared and must not be mutated.
+func (self *CowSliceSet) Values() []interface{} {
+	return self.load()
+}
+
+// Concurrency-safe version of `SliceSet.Each`. Doesn't lock: iterates over the
+// current version, which is never mutated.
+func (self *CowSliceSet) Each(fun func(int, interface{})) {
+	set := self.load()
+	set.Each(fun)
+}
+
+// Concurrency-safe version of `SliceSet.EachErr`. Doesn't lock, like `.Each`.
+func (self *CowSliceSet) EachErr(fun func(int, interface{}) error) error {
+	set := self.load()
+	return set.EachErr(fun)
+}
+
+// Concurrency-safe version of `SliceSet.EachCtx`. Doesn't lock, like `.Each`.
+func (self *CowSliceSet) EachCtx(ctx context.Context, fun func(int, interface{}) error) error {
+	set := self.load()
+	return set.EachCtx(ctx, fun)
+}
+
+// Satisfy `StringerOrdSet`. Doesn't lock.
+func (self *CowSliceSet) String() string {
+	return fmt.Sprint(self)
+}
+
+// Satisfy `StringerOrdSet`. Doesn't lock.
+func (self *CowSliceSet) GoString() string {
+	return fmt.Sprintf(`%#v`, self)
+}
+
+// Same as `LinkedSet.Version`. Doesn't lock. Incremented whenever a new slice
+// is published, including by every call to `.Update`. Since published slices
+// are never modified, iterating via `.Each` never observes a modification.
+func (self *CowSliceSet) Version() uint64 {
+	if self == nil {
+		return 0
+	}
+	return self.version.load()
+}
+
+// Runs the function against a private copy of the current `SliceSet` under the
+// write lock, then publishes the result, unless it has the same values in the
+// same order, in which case `.Version` doesn't change. Concurrent readers
+// observe either the old or the new version, never an intermediate state. The
+// function must not call methods of the same `CowSliceSet` that lock, which
+// would deadlock.
+func (self *CowSliceSet) Update(fun func(*SliceSet)) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	set := self.load()
+	next := cowCopy(set)
+	fun(&next)
+	if !cowSame(set, next) {
+		self.publish(next)
+	}
+}
+
+// Runs the function against the currently published `SliceSet` without
+// locking. The function must not mutate the set.
+func (self *CowSliceSet) View(fun func(*SliceSet)) {
+	set := self.load()
+	fun(&set)
+}
+
+func (self *CowSliceSet) load() SliceSet {
+	if self == nil {
+		return nil
+	}
+	set, _ := self.val.Load().(SliceSet)
+	return set
+}
+
+// Unless `noop` reports that the operation wouldn't change the current slice,
+// runs the function against a copy and publishes the copy. In the no-op case,
+// nothing is copied or published, and `noop` must set any results itself.
+func (self *CowSliceSet) write(noop func(SliceSet) bool, fun func(*SliceSet)) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	set := self.load()
+	if noop != nil && noop(set) {
+		return
+	}
+
+	next := cowCopy(set)
+	fun(&next)
+	self.publish(next)
+}
+
+// Must be called under the lock.
+func (self *CowSliceSet) publish(set SliceSet) {
+	self.val.Store(set)
+	self.version.add(1)
+}
+
+func cowEmpty(set SliceSet) bool { return len(set) == 0 }
+
+// Leaves room for one more value, which is enough for most single-value
+// operations.
+func cowCopy(set SliceSet) SliceSet {
+	out := make(SliceSet, len(set), len(set)+1)
+	copy(out, set)
+	return out
+}
+
+// True if both slices have the same values in the same order. NaNs are never
+// equal, so a set containing one always counts as changed.
+func cowSame(one, other SliceSet) bool {
+	if len(one) != len(other) {
+		return false
+	}
+	for i := range one {
+		if one[i] != other[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func cowSliceSetOf(set SliceSet) *CowSliceSet {
+	var out CowSliceSet
+	out.val.Store(set)
+	return &out
+}
@@ -0,0 +1,393 @@
+package gord
+
+import (
+	"context"
+	"fmt"
+)
+
+// Constructs a new `SyncSliceSet` from the provided values, deduplicating them.
+func NewSyncSliceSet(vals ...interface{}) *SyncSliceSet {
+	var set SyncSliceSet
+	for _, val := range vals {
+		set.set.Add(val)
+	}
+	return &set
+}
+
+// Concurrency-safe version of `SliceSet`. Satisfies the `OrdSet` interface. A
+// zero value is ready to use, but should never be copied. Uses a mutex for
+// both reads and writes; see `CowSliceSet` for a version with lock-free reads.
+type SyncSliceSet struct {
+	lock    statMutex
+	set     SliceSet
+	version uint64
+}
+
+var (
+	_ NavOrdSet      = (*SyncSliceSet)(nil)
+	_ StringerOrdSet = (*SyncSliceSet)(nil)
+)
+
+// Concurrency-safe version of `SliceSet.Len`.
+func (self *SyncSliceSet) Len() int {
+	if self == nil {
+		return 0
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.set.Len()
+}
+
+// Concurrency-safe version of `SliceSet.Has`.
+func (self *SyncSliceSet) Has(val interface{}) bool {
+	if self == nil {
+		return false
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.set.Has(val)
+}
+
+// Concurrency-safe version of `SliceSet.Add`.
+func (self *SyncSliceSet) Add(val interface{}) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.mutated(self.set.Added(val))
+}
+
+// Concurrency-safe version of `SliceSet.Added`.
+func (self *SyncSliceSet) Added(val interface{}) bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.mutated(self.set.Added(val))
+}
+
+// Concurrency-safe version of `SliceSet.Delete`.
+func (self *SyncSliceSet) Delete(val interface{}) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.mutated(self.set.Deleted(val))
+}
+
+// Concurrency-safe version of `SliceSet.Deleted`.
+func (self *SyncSliceSet) Deleted(val interface{}) bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.mutated(self.set.Deleted(val))
+}
+
+// Concurrency-safe version of `SliceSet.AddFirst`.
+func (self *SyncSliceSet) AddFirst(val interface{}) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.mutated(self.set.index(val) != 0)
+	self.set.AddFirst(val)
+}
+
+// Concurrency-safe version of `SliceSet.AddedFirst`.
+func (self *SyncSliceSet) AddedFirst(val interface{}) bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.mutated(self.set.index(val) != 0)
+	return self.set.AddedFirst(val)
+}
+
+// Concurrency-safe version of `SliceSet.AddLast`.
+func (self *SyncSliceSet) AddLast(val interface{}) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.mutated(!self.isLast(val))
+	self.set.AddLast(val)
+}
+
+// Concurrency-safe version of `SliceSet.AddedLast`.
+func (self *SyncSliceSet) AddedLast(val interface{}) bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.mutated(!self.isLast(val))
+	return self.set.AddedLast(val)
+}
+
+// Concurrency-safe version of `SliceSet.Swap`.
+func (self *SyncSliceSet) Swap(one, other interface{}) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.mutated(self.set.Swapped(one, other) && one != other)
+}
+
+// Concurrency-safe version of `SliceSet.Swapped`.
+func (self *SyncSliceSet) Swapped(one, other interface{}) bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	ok := self.set.Swapped(one, other)
+	self.mutated(ok && one != other)
+	return ok
+}
+
+// Concurrency-safe version of `SliceSet.MoveTo`.
+func (self *SyncSliceSet) MoveTo(val interface{}, index int) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.mutated(self.set.MovedTo(val, index))
+}
+
+// Concurrency-safe version of `SliceSet.MovedTo`.
+func (self *SyncSliceSet) MovedTo(val interface{}, index int) bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.mutated(self.set.MovedTo(val, index))
+}
+
+// Concurrency-safe version of `SliceSet.Rotate`.
+func (self *SyncSliceSet) Rotate(count int) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.set.Rotate(count)
+	self.mutated(len(self.set) > 1 && count%len(self.set) != 0)
+}
+
+// Concurrency-safe version of `SliceSet.PoppedFirst`.
+func (self *SyncSliceSet) PoppedFirst() (interface{}, bool) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	val, ok := self.set.PoppedFirst()
+	return val, self.mutated(ok)
+}
+
+// Concurrency-safe version of `SliceSet.PoppedLast`.
+func (self *SyncSliceSet) PoppedLast() (interface{}, bool) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	val, ok := self.set.PoppedLast()
+	return val, self.mutated(ok)
+}
+
+// Concurrency-safe version of `SliceSet.PoppedFirstN`. Takes the lock once.
+func (self *SyncSliceSet) PoppedFirstN(count int) []interface{} {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	out := self.set.PoppedFirstN(count)
+	self.mutated(len(out) > 0)
+	return out
+}
+
+// Concurrency-safe version of `SliceSet.PoppedLastN`. Takes the lock once.
+func (self *SyncSliceSet) PoppedLastN(count int) []interface{} {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	out := self.set.PoppedLastN(count)
+	self.mutated(len(out) > 0)
+	return out
+}
+
+// Concurrency-safe version of `SliceSet.Drain`. Empties the set atomically.
+func (self *SyncSliceSet) Drain() []interface{} {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	out := self.set.Drain()
+	self.mutated(len(out) > 0)
+	return out
+}
+
+// Concurrency-safe version of `SliceSet.First`.
+func (self *SyncSliceSet) First() (interface{}, bool) {
+	if self == nil {
+		return nil, false
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.set.First()
+}
+
+// Concurrency-safe version of `SliceSet.Last`.
+func (self *SyncSliceSet) Last() (interface{}, bool) {
+	if self == nil {
+		return nil, false
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.set.Last()
+}
+
+// Concurrency-safe version of `SliceSet.Next`.
+func (self *SyncSliceSet) Next(val interface{}) (interface{}, bool) {
+	if self == nil {
+		return nil, false
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.set.Next(val)
+}
+
+// Concurrency-safe version of `SliceSet.Prev`.
+func (self *SyncSliceSet) Prev(val interface{}) (interface{}, bool) {
+	if self == nil {
+		return nil, false
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.set.Prev(val)
+}
+
+// Concurrency-safe version of `SliceSet.Slice`. The result is a consistent
+// snapshot.
+func (self *SyncSliceSet) Slice(start, end int) *SyncSliceSet {
+	var out SyncSliceSet
+	if self == nil {
+		return &out
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	out.set = *self.set.Slice(start, end)
+	return &out
+}
+
+// Concurrency-safe version of `SliceSet.Between`. The result is a consistent
+// snapshot.
+func (self *SyncSliceSet) Between(first, last interface{}) *SyncSliceSet {
+	var out SyncSliceSet
+	if self == nil {
+		return &out
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	out.set = *self.set.Between(first, last)
+	return &out
+}
+
+// Concurrency-safe version of `SliceSet.Take`.
+func (self *SyncSliceSet) Take(count int) *SyncSliceSet {
+	return self.Slice(0, count)
+}
+
+// Concurrency-safe version of `SliceSet.TakeLast`. Unlike
+// `.Slice(set.Len()-count, set.Len())`, reads the length under the same lock.
+func (self *SyncSliceSet) TakeLast(count int) *SyncSliceSet {
+	var out SyncSliceSet
+	if self == nil {
+		return &out
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	out.set = *self.set.TakeLast(count)
+	return &out
+}
+
+// Concurrency-safe version of `SliceSet.Drop`. Unlike `.Slice(count,
+// set.Len())`, reads the length under the same lock.
+func (self *SyncSliceSet) Drop(count int) *SyncSliceSet {
+	var out SyncSliceSet
+	if self == nil {
+		return &out
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	out.set = *self.set.Drop(count)
+	return &out
+}
+
+// Concurrency-safe version of `SliceSet.Values`. Unlike the original, returns a
+// copy, which is OK to mutate.
+func (self *SyncSliceSet) Values() []interface{} {
+	if self == nil {
+		return nil
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	out := make([]interface{}, len(self.set))
+	copy(out, self.set)
+	return out
+}
+
+// Concurrency-safe version of `SliceSet.Each`. Holds the lock throughout,
+// so the function must not use the set. To iterate without blocking other
+// users, use `.Snapshot`.
+func (self *SyncSliceSet) Each(fun func(int, interface{})) {
+	if self == nil {
+		return
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.set.Each(fun)
+}
+
+// Concurrency-safe version of `SliceSet.EachErr`. Holds the lock
+// throughout, like `.Each`.
+func (self *SyncSliceSet) EachErr(fun func(int, interface{}) error) error {
+	if self == nil {
+		return nil
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.set.EachErr(fun)
+}
+
+// Concurrency-safe version of `SliceSet.EachCtx`. Holds the lock
+// throughout, like `.Each`.
+func (self *SyncSliceSet) EachCtx(ctx context.Context, fun func(int, interface{}) error) error {
+	return self.EachErr(eachCtx(ctx, fun))
+}
+
+// Same as `SyncLinkedSet.Snapshot`.
+func (self *SyncSliceSet) Snapshot() SliceSet {
+	return self.Values()
+}
+
+// Same as `LinkedSet.Version`. Also incremented by every call to `.Update`,
+// even if it changes nothing.
+func (self *SyncSliceSet) Version() uint64 {
+	if self == nil {
+		return 0
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.version
+}
+
+// Concurrency-safe version of `SliceSet.String`.
+func (self *SyncSliceSet) String() string {
+	return fmt.Sprint(self)
+}
+
+// Concurrency-safe version of `SliceSet.GoString`.
+func (self *SyncSliceSet) GoString() string {
+	return fmt.Sprintf(`%#v`, self)
+}
+
+// Runs the function against the inner `SliceSet` under a single lock
+// acquisition, making multi-step sequences atomic. The function must not
+// retain the `*SliceSet` or its backing array after returning, and must not
+// call methods of the same `SyncSliceSet`, which would deadlock.
+func (self *SyncSliceSet) Update(fun func(*SliceSet)) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	defer self.mutated(true)
+	fun(&self.set)
+}
+
+// Same as `.Update`, but for read-only access. The function must not mutate
+// the set.
+func (self *SyncSliceSet) View(fun func(*SliceSet)) {
+	if self == nil {
+		fun(nil)
+		return
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	fun(&self.set)
+}
+
+// Must be called under the lock. Increments the version if the set was
+// modified, and passes the flag through.
+func (self *SyncSliceSet) mutated(ok bool) bool {
+	if ok {
+		self.version++
+	}
+	return ok
+}
+
+// Must be called under the lock.
+func (self *SyncSliceSet) isLast(val interface{}) bool {
+	index := self.set.index(val)
+	return index >= 0 && index == len(self.set)-1
+}
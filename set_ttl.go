@@ -0,0 +1,296 @@
+package gord
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Source of the current time for `TTLSet`. Useful for deterministic tests.
+type Clock interface{ Now() time.Time }
+
+/*
+Concurrency-safe set where each value expires after a time-to-live. Values are
+ordered by expiry, earliest first; values with the same expiry keep the order
+of addition. Expired values are removed from the front, in order, at the start
+of every method that reads the full contents or modifies the set, or by
+`.Expire`, or by a background `.Janitor`. `.Has` and `.Expiry` ignore expired
+values even before they're removed.
+
+The exported fields must be set before using the set concurrently. A zero value
+is ready to use; without a TTL, values never expire.
+*/
+type TTLSet struct {
+	// Time-to-live used by `.Add` and `.Added`. Zero or negative means values
+	// never expire; such values are ordered after every expiring value.
+	TTL time.Duration
+
+	// Optional source of time. Defaults to `time.Now`.
+	Clock Clock
+
+	// Optional callback, invoked for every expired value in order of expiry,
+	// after releasing the lock; it may safely use the set. Not invoked for
+	// values removed via `.Delete`.
+	OnExpire func(interface{})
+
+	lock statMutex
+	set  map[interface{}]*list.Element
+	ord  list.List
+}
+
+var _ Set = (*TTLSet)(nil)
+
+type ttlEntry struct {
+	val      interface{}
+	deadline time.Time
+}
+
+// Returns the current number of unexpired values.
+func (self *TTLSet) Len() (out int) {
+	if self == nil {
+		return 0
+	}
+	self.update(func(time.Time) { out = len(self.set) })
+	return
+}
+
+// True if the value is present and hasn't expired yet. Doesn't remove expired
+// values.
+func (self *TTLSet) Has(val interface{}) bool {
+	_, ok := self.Expiry(val)
+	return ok
+}
+
+// If the value is present and hasn't expired yet, returns its expiry and
+// `true`. A zero time means it never expires. Doesn't remove expired values.
+func (self *TTLSet) Expiry(val interface{}) (time.Time, bool) {
+	if self == nil {
+		return time.Time{}, false
+	}
+
+	now := self.now()
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	elem := self.set[val]
+	if elem == nil {
+		return time.Time{}, false
+	}
+	deadline := elem.Value.(ttlEntry).deadline
+	if ttlExpired(deadline, now) {
+		return time.Time{}, false
+	}
+	return deadline, true
+}
+
+// Void version of `.Added`.
+func (self *TTLSet) Add(val interface{}) {
+	_ = self.Added(val)
+}
+
+// Same as `.AddedTTL` with `set.TTL`.
+func (self *TTLSet) Added(val interface{}) bool {
+	return self.AddedTTL(val, self.TTL)
+}
+
+// Void version of `.AddedTTL`.
+func (self *TTLSet) AddTTL(val interface{}, ttl time.Duration) {
+	_ = self.AddedTTL(val, ttl)
+}
+
+// If the value is missing or expired, adds it with the given time-to-live and
+// returns `true`. Zero or negative TTL means it never expires. If the value is
+// present, returns `false` without changing its expiry.
+func (self *TTLSet) AddedTTL(val interface{}, ttl time.Duration) (out bool) {
+	self.update(func(now time.Time) {
+		self.init()
+		if self.set[val] != nil {
+			return
+		}
+
+		entry := ttlEntry{val: val}
+		if ttl > 0 {
+			entry.deadline = now.Add(ttl)
+		}
+		self.set[val] = self.insert(entry)
+		out = true
+	})
+	return
+}
+
+// Void version of `.Deleted`.
+func (self *TTLSet) Delete(val interface{}) {
+	_ = self.Deleted(val)
+}
+
+// If the value is present and hasn't expired yet, removes it without invoking
+// `OnExpire` and returns `true`. Otherwise returns `false`.
+func (self *TTLSet) Deleted(val interface{}) (out bool) {
+	if self == nil {
+		return false
+	}
+	self.update(func(time.Time) {
+		elem := self.set[val]
+		if elem != nil {
+			delete(self.set, val)
+			self.ord.Remove(elem)
+			out = true
+		}
+	})
+	return
+}
+
+// Removes every expired value, invoking `OnExpire` for each, and returns how
+// many were removed.
+func (self *TTLSet) Expire() int {
+	if self == nil {
+		return 0
+	}
+	expired := self.locked(func(time.Time) {})
+	self.notify(expired)
+	return len(expired)
+}
+
+// Returns the unexpired values in order of expiry, earliest first.
+func (self *TTLSet) Values() (out []interface{}) {
+	if self == nil {
+		return nil
+	}
+	self.update(func(time.Time) {
+		out = make([]interface{}, 0, self.ord.Len())
+		for elem := self.ord.Front(); elem != nil; elem = elem.Next() {
+			out = append(out, elem.Value.(ttlEntry).val)
+		}
+	})
+	return
+}
+
+// Prints the unexpired values in order of expiry, like a slice.
+func (self *TTLSet) String() string {
+	return fmt.Sprint(self.Values())
+}
+
+/*
+Starts a goroutine that calls `.Expire` at the given interval, measured in real
+time regardless of `set.Clock`, until the returned janitor is stopped. Without
+a janitor, expired values are removed only when the set is used, and
+`OnExpire` may be invoked late. Panics if the interval isn't positive.
+*/
+func (self *TTLSet) Janitor(interval time.Duration) *TTLJanitor {
+	if interval <= 0 {
+		panic(fmt.Errorf(`[gord] invalid janitor interval %v: must be positive`, interval))
+	}
+
+	janitor := &TTLJanitor{stop: make(chan struct{}), done: make(chan struct{})}
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		defer close(janitor.done)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				_ = self.Expire()
+			case <-janitor.stop:
+				return
+			}
+		}
+	}()
+
+	return janitor
+}
+
+// Background goroutine started by `TTLSet.Janitor`.
+type TTLJanitor struct {
+	once sync.Once
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Stops the janitor and waits for its goroutine to exit. Idempotent. Must not
+// be called from `OnExpire`.
+func (self *TTLJanitor) Stop() {
+	self.once.Do(func() { close(self.stop) })
+	<-self.done
+}
+
+// Removes expired values, then runs the function under the lock, then reports
+// the expired values to `OnExpire` after unlocking.
+func (self *TTLSet) update(fun func(time.Time)) {
+	self.notify(self.locked(fun))
+}
+
+func (self *TTLSet) locked(fun func(time.Time)) []interface{} {
+	now := self.now()
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	expired := self.expire(now)
+	fun(now)
+	return expired
+}
+
+// Expiring values are sorted, so the expired ones are always at the front.
+func (self *TTLSet) expire(now time.Time) (out []interface{}) {
+	for {
+		elem := self.ord.Front()
+		if elem == nil {
+			return
+		}
+
+		entry := elem.Value.(ttlEntry)
+		if !ttlExpired(entry.deadline, now) {
+			return
+		}
+
+		delete(self.set, entry.val)
+		self.ord.Remove(elem)
+		out = append(out, entry.val)
+	}
+}
+
+func (self *TTLSet) notify(expired []interface{}) {
+	if self.OnExpire == nil {
+		return
+	}
+	for _, val := range expired {
+		self.OnExpire(val)
+	}
+}
+
+// Finds the position from the back, since the default TTL keeps new values
+// sorted, making this O(1) in the common case.
+func (self *TTLSet) insert(entry ttlEntry) *list.Element {
+	for mark := self.ord.Back(); mark != nil; mark = mark.Prev() {
+		if !ttlBefore(entry.deadline, mark.Value.(ttlEntry).deadline) {
+			return self.ord.InsertAfter(entry, mark)
+		}
+	}
+	return self.ord.PushFront(entry)
+}
+
+func (self *TTLSet) now() time.Time {
+	if self.Clock != nil {
+		return self.Clock.Now()
+	}
+	return time.Now()
+}
+
+func (self *TTLSet) init() {
+	if self.set == nil {
+		self.set = map[interface{}]*list.Element{}
+	}
+}
+
+// A zero deadline means "never", which comes after every other deadline.
+func ttlBefore(one, other time.Time) bool {
+	if one.IsZero() {
+		return false
+	}
+	return other.IsZero() || one.Before(other)
+}
+
+func ttlExpired(deadline, now time.Time) bool {
+	return !deadline.IsZero() && !now.Before(deadline)
+}
@@ -0,0 +1,358 @@
+package gord
+
+import "fmt"
+
+// `TypedLinkedSet` specialized for ints.
+type LinkedIntSet = TypedLinkedSet[int]
+
+// `TypedLinkedSet` specialized for strings.
+type LinkedStringSet = TypedLinkedSet[string]
+
+// Constructs a new `LinkedIntSet` from the provided values, deduplicating them.
+func NewLinkedIntSet(vals ...int) *LinkedIntSet { return NewTypedLinkedSet(vals...) }
+
+// Constructs a new `LinkedStringSet` from the provided values, deduplicating
+// them.
+func NewLinkedStringSet(vals ...string) *LinkedStringSet { return NewTypedLinkedSet(vals...) }
+
+// Constructs a new `TypedLinkedSet` from the provided values, deduplicating
+// them.
+func NewTypedLinkedSet[T comparable](vals ...T) *TypedLinkedSet[T] {
+	var set TypedLinkedSet[T]
+	for _, val := range vals {
+		set.Add(val)
+	}
+	return &set
+}
+
+/*
+Version of `LinkedSet` for values of a single type, with the same behavior and
+performance characteristics, but without boxing values into interfaces: the
+map is keyed by `T`, the list nodes store `T`, and `.Values` returns `[]T`.
+For ints and strings this avoids an allocation per value, and spares the
+garbage collector from scanning boxed values.
+
+The methods take and return `T`, so this doesn't satisfy `OrdSet` directly; use
+`.OrdSet` to plug it into code written for `OrdSet`.
+
+Concurrency-unsafe. A zero value is ready to use, but should not be copied
+after the first mutation.
+*/
+type TypedLinkedSet[T comparable] struct {
+	set   map[T]*typedNode[T]
+	first *typedNode[T]
+	last  *typedNode[T]
+}
+
+// Intrusive list node. Unlike `container/list`, stores the value without
+// boxing it.
+type typedNode[T comparable] struct {
+	val  T
+	prev *typedNode[T]
+	next *typedNode[T]
+}
+
+// Same as `LinkedSet.Len`.
+func (self *TypedLinkedSet[T]) Len() int {
+	if self == nil {
+		return 0
+	}
+	return len(self.set)
+}
+
+// Same as `LinkedSet.Has`.
+func (self *TypedLinkedSet[T]) Has(val T) bool {
+	return self != nil && self.set[val] != nil
+}
+
+// Same as `LinkedSet.Add`.
+func (self *TypedLinkedSet[T]) Add(val T) {
+	_ = self.Added(val)
+}
+
+// Same as `LinkedSet.Added`.
+func (self *TypedLinkedSet[T]) Added(val T) bool {
+	if self.Has(val) {
+		return false
+	}
+	self.pushBack(val)
+	return true
+}
+
+// Same as `LinkedSet.Delete`.
+func (self *TypedLinkedSet[T]) Delete(val T) {
+	_ = self.Deleted(val)
+}
+
+// Same as `LinkedSet.Deleted`.
+func (self *TypedLinkedSet[T]) Deleted(val T) bool {
+	if self == nil {
+		return false
+	}
+	node := self.set[val]
+	if node == nil {
+		return false
+	}
+	self.unlink(node)
+	delete(self.set, val)
+	return true
+}
+
+// Same as `LinkedSet.AddFirst`.
+func (self *TypedLinkedSet[T]) AddFirst(val T) {
+	_ = self.AddedFirst(val)
+}
+
+// Same as `LinkedSet.AddedFirst`.
+func (self *TypedLinkedSet[T]) AddedFirst(val T) bool {
+	node := self.set[val]
+	if node == nil {
+		self.pushFront(val)
+		return true
+	}
+	if node != self.first {
+		self.unlink(node)
+		self.linkFront(node)
+	}
+	return false
+}
+
+// Same as `LinkedSet.AddLast`.
+func (self *TypedLinkedSet[T]) AddLast(val T) {
+	_ = self.AddedLast(val)
+}
+
+// Same as `LinkedSet.AddedLast`.
+func (self *TypedLinkedSet[T]) AddedLast(val T) bool {
+	node := self.set[val]
+	if node == nil {
+		self.pushBack(val)
+		return true
+	}
+	if node != self.last {
+		self.unlink(node)
+		self.linkBack(node)
+	}
+	return false
+}
+
+// Same as `LinkedSet.PoppedFirst`.
+func (self *TypedLinkedSet[T]) PoppedFirst() (T, bool) {
+	if self == nil {
+		var zero T
+		return zero, false
+	}
+	return self.popped(self.first)
+}
+
+// Same as `LinkedSet.PoppedLast`.
+func (self *TypedLinkedSet[T]) PoppedLast() (T, bool) {
+	if self == nil {
+		var zero T
+		return zero, false
+	}
+	return self.popped(self.last)
+}
+
+// Same as `LinkedSet.First`.
+func (self *TypedLinkedSet[T]) First() (T, bool) {
+	if self == nil {
+		var zero T
+		return zero, false
+	}
+	return nodeValue(self.first)
+}
+
+// Same as `LinkedSet.Last`.
+func (self *TypedLinkedSet[T]) Last() (T, bool) {
+	if self == nil {
+		var zero T
+		return zero, false
+	}
+	return nodeValue(self.last)
+}
+
+// Same as `LinkedSet.Next`.
+func (self *TypedLinkedSet[T]) Next(val T) (T, bool) {
+	if !self.Has(val) {
+		var zero T
+		return zero, false
+	}
+	return nodeValue(self.set[val].next)
+}
+
+// Same as `LinkedSet.Prev`.
+func (self *TypedLinkedSet[T]) Prev(val T) (T, bool) {
+	if !self.Has(val) {
+		var zero T
+		return zero, false
+	}
+	return nodeValue(self.set[val].prev)
+}
+
+// Same as `LinkedSet.Values`, but typed: returns a newly allocated `[]T`.
+func (self *TypedLinkedSet[T]) Values() []T {
+	out := make([]T, 0, self.Len())
+	if self == nil {
+		return out
+	}
+	for node := self.first; node != nil; node = node.next {
+		out = append(out, node.val)
+	}
+	return out
+}
+
+// Prints the values, like a slice.
+func (self *TypedLinkedSet[T]) String() string {
+	return fmt.Sprint(self.Values())
+}
+
+/*
+Returns an adapter that satisfies `NavOrdSet` by boxing and unboxing values,
+backed by this set. Adding a value of another type panics; looking up or
+deleting one behaves as if it were missing. Useful for passing typed sets to
+code written for `OrdSet`, at the cost of boxing values again.
+*/
+func (self *TypedLinkedSet[T]) OrdSet() NavOrdSet {
+	return typedOrdSet[T]{self}
+}
+
+func (self *TypedLinkedSet[T]) popped(node *typedNode[T]) (T, bool) {
+	if node == nil {
+		var zero T
+		return zero, false
+	}
+	self.unlink(node)
+	delete(self.set, node.val)
+	return node.val, true
+}
+
+func (self *TypedLinkedSet[T]) pushFront(val T) {
+	node := &typedNode[T]{val: val}
+	self.index(node)
+	self.linkFront(node)
+}
+
+func (self *TypedLinkedSet[T]) pushBack(val T) {
+	node := &typedNode[T]{val: val}
+	self.index(node)
+	self.linkBack(node)
+}
+
+func (self *TypedLinkedSet[T]) index(node *typedNode[T]) {
+	if self.set == nil {
+		self.set = map[T]*typedNode[T]{}
+	}
+	self.set[node.val] = node
+}
+
+func (self *TypedLinkedSet[T]) linkFront(node *typedNode[T]) {
+	node.prev, node.next = nil, self.first
+	if self.first != nil {
+		self.first.prev = node
+	} else {
+		self.last = node
+	}
+	self.first = node
+}
+
+func (self *TypedLinkedSet[T]) linkBack(node *typedNode[T]) {
+	node.prev, node.next = self.last, nil
+	if self.last != nil {
+		self.last.next = node
+	} else {
+		self.first = node
+	}
+	self.last = node
+}
+
+func (self *TypedLinkedSet[T]) unlink(node *typedNode[T]) {
+	if node.prev != nil {
+		node.prev.next = node.next
+	} else {
+		self.first = node.next
+	}
+	if node.next != nil {
+		node.next.prev = node.prev
+	} else {
+		self.last = node.prev
+	}
+	node.prev, node.next = nil, nil
+}
+
+func nodeValue[T comparable](node *typedNode[T]) (T, bool) {
+	if node == nil {
+		var zero T
+		return zero, false
+	}
+	return node.val, true
+}
+
+// Implementation of `TypedLinkedSet.OrdSet`.
+type typedOrdSet[T comparable] struct{ set *TypedLinkedSet[T] }
+
+var _ NavOrdSet = typedOrdSet[int]{}
+
+func (self typedOrdSet[T]) Len() int { return self.set.Len() }
+
+func (self typedOrdSet[T]) Has(val interface{}) bool {
+	typed, ok := val.(T)
+	return ok && self.set.Has(typed)
+}
+
+func (self typedOrdSet[T]) Add(val interface{})             { self.set.Add(val.(T)) }
+func (self typedOrdSet[T]) Added(val interface{}) bool      { return self.set.Added(val.(T)) }
+func (self typedOrdSet[T]) AddFirst(val interface{})        { self.set.AddFirst(val.(T)) }
+func (self typedOrdSet[T]) AddedFirst(val interface{}) bool { return self.set.AddedFirst(val.(T)) }
+func (self typedOrdSet[T]) AddLast(val interface{})         { self.set.AddLast(val.(T)) }
+func (self typedOrdSet[T]) AddedLast(val interface{}) bool  { return self.set.AddedLast(val.(T)) }
+
+func (self typedOrdSet[T]) Delete(val interface{}) { _ = self.Deleted(val) }
+
+func (self typedOrdSet[T]) Deleted(val interface{}) bool {
+	typed, ok := val.(T)
+	return ok && self.set.Deleted(typed)
+}
+
+func (self typedOrdSet[T]) PoppedFirst() (interface{}, bool) { return boxed(self.set.PoppedFirst()) }
+func (self typedOrdSet[T]) PoppedLast() (interface{}, bool)  { return boxed(self.set.PoppedLast()) }
+func (self typedOrdSet[T]) First() (interface{}, bool)       { return boxed(self.set.First()) }
+func (self typedOrdSet[T]) Last() (interface{}, bool)        { return boxed(self.set.Last()) }
+
+func (self typedOrdSet[T]) Next(val interface{}) (interface{}, bool) {
+	typed, ok := val.(T)
+	if !ok {
+		return nil, false
+	}
+	return boxed(self.set.Next(typed))
+}
+
+func (self typedOrdSet[T]) Prev(val interface{}) (interface{}, bool) {
+	typed, ok := val.(T)
+	if !ok {
+		return nil, false
+	}
+	return boxed(self.set.Prev(typed))
+}
+
+func (self typedOrdSet[T]) Values() []interface{} {
+	out := make([]interface{}, 0, self.set.Len())
+	if self.set == nil {
+		return out
+	}
+	for node := self.set.first; node != nil; node = node.next {
+		out = append(out, node.val)
+	}
+	return out
+}
+
+func (self typedOrdSet[T]) String() string { return self.set.String() }
+
+// Missing values are reported as `nil`, like in `OrdSet`, rather than the zero
+// value of `T`.
+func boxed[T any](val T, ok bool) (interface{}, bool) {
+	if !ok {
+		return nil, false
+	}
+	return val, true
+}
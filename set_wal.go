@@ -0,0 +1,694 @@
+package gord
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// When `WalSet` calls fsync on its log. See `WalOptions`.
+type WalSync int
+
+const (
+	// Fsync after every logged mutation, before the method returns. Mutations
+	// survive power loss as soon as they're reported. The default.
+	WalSyncAlways WalSync = iota
+
+	// Fsync only in `.Sync`, `.Compact` and `.Close`. Mutations survive a crash
+	// of the process, since they're already written to the OS, but recent ones
+	// may be lost on power loss. Much faster.
+	WalSyncNever
+)
+
+// Options for `OpenWalSet`. A zero value is ready to use.
+type WalOptions struct {
+	// Optional; defaults to `JSONCodec`.
+	Codec Codec
+
+	// Fsync policy; defaults to `WalSyncAlways`.
+	Sync WalSync
+
+	// If positive, the log is compacted automatically when it holds more than
+	// this many records and more than twice as many records as values. Zero
+	// means only `.Compact` compacts the log.
+	CompactAfter int
+}
+
+/*
+Crash-safe `OrdSet` persisted in a local file. Every mutation is appended to a
+write-ahead log before it's applied to an in-memory `LinkedSet`, and
+`OpenWalSet` replays the log to restore the set. Reads never touch the file.
+
+Each record is checksummed. When the last record was torn by a crash, the log
+is truncated before it on open, recovering every mutation that was fully
+written. A record that fails its checksum is treated as torn only when nothing
+but zeros follows it. Corruption in the middle of the log, and records that
+pass their checksums but fail to decode, are reported as errors, leaving the
+file untouched.
+
+`.Compact` rewrites the log as a snapshot of the current values, replacing it
+via atomic rename, so a crash leaves either the old log or the new one.
+
+Values must survive a round trip through the codec: decoding their encoding
+must produce an equal value. With the default `JSONCodec`, this rules out ints
+and structs, which decode as `float64` and maps; use a custom `Codec` for
+those. A value that fails to encode or to round-trip isn't logged or applied,
+and its mutation reports `false`, but this only affects that call; see `.Err`.
+
+I/O errors are sticky: after a failed write or automatic compaction,
+mutations have no effect and report `false`, and `.Err` and `.Close` return the
+error. If a record is written but fsync fails, the mutation is still applied,
+since replaying the log may restore it, and the error becomes sticky. The
+in-memory set always matches the log.
+
+Concurrency-safe. Must be created via `OpenWalSet`, and only one `WalSet` may
+use a file at a time.
+*/
+type WalSet struct {
+	lock    statMutex
+	set     LinkedSet
+	opts    WalOptions
+	path    string
+	file    *os.File
+	records int
+	err     error
+	buf     []byte
+
+	// Encoding error of the latest mutation. Unlike `err`, not sticky.
+	encodeErr error
+}
+
+var _ NavOrdSet = (*WalSet)(nil)
+
+/*
+Opens the log at the given path, creating it if missing, and replays it. If the
+log ends with a torn record, truncates it. Returns an error if the file isn't
+a `WalSet` log, if a record that isn't the last one is corrupted, or if a
+record can't be decoded.
+*/
+func OpenWalSet(path string, opts WalOptions) (*WalSet, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o666)
+	if err != nil {
+		return nil, err
+	}
+
+	set := &WalSet{opts: opts, path: path, file: file}
+	err = set.replay()
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return set, nil
+}
+
+// Concurrency-safe version of `LinkedSet.Len`.
+func (self *WalSet) Len() int {
+	if self == nil {
+		return 0
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.set.Len()
+}
+
+// Concurrency-safe version of `LinkedSet.Has`.
+func (self *WalSet) Has(val interface{}) bool {
+	if self == nil {
+		return false
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.set.Has(val)
+}
+
+// Satisfy `Set`.
+func (self *WalSet) Add(val interface{}) {
+	_ = self.Added(val)
+}
+
+// Satisfy `Set`. Logs the mutation only if the value is missing.
+func (self *WalSet) Added(val interface{}) bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return !self.set.Has(val) && self.mutated(walAdd, val)
+}
+
+// Satisfy `Set`.
+func (self *WalSet) Delete(val interface{}) {
+	_ = self.Deleted(val)
+}
+
+// Satisfy `Set`. Logs the mutation only if the value is present.
+func (self *WalSet) Deleted(val interface{}) bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.set.Has(val) && self.mutated(walDelete, val)
+}
+
+// Satisfy `OrdSet`.
+func (self *WalSet) AddFirst(val interface{}) {
+	_ = self.AddedFirst(val)
+}
+
+// Satisfy `OrdSet`. Logs the mutation unless the value is already first.
+func (self *WalSet) AddedFirst(val interface{}) bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	first, ok := self.set.First()
+	if ok && first == val {
+		return false
+	}
+	missing := !self.set.Has(val)
+	return self.mutated(walFirst, val) && missing
+}
+
+// Satisfy `OrdSet`.
+func (self *WalSet) AddLast(val interface{}) {
+	_ = self.AddedLast(val)
+}
+
+// Satisfy `OrdSet`. Logs the mutation unless the value is already last.
+func (self *WalSet) AddedLast(val interface{}) bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	last, ok := self.set.Last()
+	if ok && last == val {
+		return false
+	}
+	missing := !self.set.Has(val)
+	return self.mutated(walLast, val) && missing
+}
+
+// Satisfy `OrdSet`. Logs the removal as a deletion of the popped value.
+func (self *WalSet) PoppedFirst() (interface{}, bool) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	val, ok := self.set.First()
+	if !ok || !self.mutated(walDelete, val) {
+		return nil, false
+	}
+	return val, true
+}
+
+// Satisfy `OrdSet`. Logs the removal as a deletion of the popped value.
+func (self *WalSet) PoppedLast() (interface{}, bool) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	val, ok := self.set.Last()
+	if !ok || !self.mutated(walDelete, val) {
+		return nil, false
+	}
+	return val, true
+}
+
+// Concurrency-safe version of `LinkedSet.First`.
+func (self *WalSet) First() (interface{}, bool) {
+	if self == nil {
+		return nil, false
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.set.First()
+}
+
+// Concurrency-safe version of `LinkedSet.Last`.
+func (self *WalSet) Last() (interface{}, bool) {
+	if self == nil {
+		return nil, false
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.set.Last()
+}
+
+// Concurrency-safe version of `LinkedSet.Next`.
+func (self *WalSet) Next(val interface{}) (interface{}, bool) {
+	if self == nil {
+		return nil, false
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.set.Next(val)
+}
+
+// Concurrency-safe version of `LinkedSet.Prev`.
+func (self *WalSet) Prev(val interface{}) (interface{}, bool) {
+	if self == nil {
+		return nil, false
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.set.Prev(val)
+}
+
+// Concurrency-safe version of `LinkedSet.Values`.
+func (self *WalSet) Values() []interface{} {
+	if self == nil {
+		return nil
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.set.Values()
+}
+
+// Prints the values, like a slice.
+func (self *WalSet) String() string {
+	return fmt.Sprint(self.Values())
+}
+
+// Returns the sticky I/O error, if any. Otherwise, if the latest mutation
+// failed to encode its value or the value didn't round-trip, returns that
+// error, until the next mutation.
+func (self *WalSet) Err() error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if self.err != nil {
+		return self.err
+	}
+	return self.encodeErr
+}
+
+// Fsyncs the log. Needed only with `WalSyncNever`.
+func (self *WalSet) Sync() error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if self.err != nil {
+		return self.err
+	}
+	if self.file == nil {
+		return errWalClosed
+	}
+	return self.failed(self.file.Sync())
+}
+
+/*
+Rewrites the log as one record per value, in order, discarding the history of
+mutations. Writes to a temporary file next to the log, fsyncs it, and renames
+it over the log. If this fails before renaming, the old log remains in use, and
+the error isn't sticky.
+*/
+func (self *WalSet) Compact() error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.compact()
+}
+
+// Fsyncs and closes the log. Afterwards, the set can still be read, but
+// mutations have no effect. Returns the sticky error, if any.
+func (self *WalSet) Close() error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if self.file == nil {
+		return self.err
+	}
+
+	err := self.file.Sync()
+	if err == nil {
+		err = self.file.Close()
+	} else {
+		_ = self.file.Close()
+	}
+	self.file = nil
+
+	if self.err != nil {
+		return self.err
+	}
+	return err
+}
+
+// Satisfy `StatsSet`, with lock contention counters. Doesn't include the file.
+func (self *WalSet) Stats() Stats {
+	if self == nil {
+		return Stats{}
+	}
+	self.lock.Lock()
+	out := self.set.Stats()
+	self.lock.Unlock()
+	self.lock.stats(&out)
+	return out
+}
+
+// Same as `LinkedSet.Validate`. Doesn't check the file.
+func (self *WalSet) Validate() error {
+	if self == nil {
+		return nil
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.set.validate(`*WalSet`)
+}
+
+/*
+Log format: the magic string, followed by records. Each record is a header of
+two little-endian uint32s, the payload length and its CRC-32C checksum,
+followed by the payload: an operation byte and the encoded value. Operations
+are replayed via the corresponding `LinkedSet` methods.
+*/
+const (
+	walMagic      = "gordwal1"
+	walHeaderSize = 8
+	walMaxPayload = 1 << 30
+
+	walAdd    byte = 'a'
+	walFirst  byte = 'f'
+	walLast   byte = 'l'
+	walDelete byte = 'd'
+)
+
+var (
+	walTable     = crc32.MakeTable(crc32.Castagnoli)
+	errWalClosed = errors.New(`[gord] WalSet is closed`)
+	errWalTorn   = errors.New(`[gord] torn WalSet record`)
+	errWalBad    = errors.New(`[gord] corrupted WalSet record`)
+)
+
+// Logs the mutation, then applies it. Returns `false` if logging failed.
+// Only I/O failures are sticky. A failed fsync comes after the record is
+// written, so the mutation is applied regardless.
+func (self *WalSet) mutated(op byte, val interface{}) bool {
+	if self.err != nil || self.file == nil {
+		return false
+	}
+
+	buf, err := self.record(self.buf[:0], op, val)
+	self.encodeErr = err
+	if err != nil {
+		return false
+	}
+	self.buf = buf
+
+	_, err = self.file.Write(buf)
+	if err != nil {
+		self.err = err
+		return false
+	}
+
+	walApply(&self.set, op, val)
+	self.records++
+
+	if self.opts.Sync == WalSyncAlways {
+		err = self.file.Sync()
+		if err != nil {
+			self.err = err
+			return true
+		}
+	}
+
+	if self.opts.CompactAfter > 0 &&
+		self.records > self.opts.CompactAfter &&
+		self.records > 2*self.set.Len() {
+		_ = self.failed(self.compact())
+	}
+	return true
+}
+
+func (self *WalSet) record(buf []byte, op byte, val interface{}) ([]byte, error) {
+	body, err := self.codec().Encode(val)
+	if err != nil {
+		return buf, fmt.Errorf(`[gord] unable to encode %#v for WalSet: %w`, val, err)
+	}
+
+	// Replaying must restore the same value, or the log wouldn't match the set.
+	decoded, err := self.codec().Decode(body)
+	if err != nil {
+		return buf, fmt.Errorf(`[gord] unable to decode the encoding of %#v for WalSet: %w`, val, err)
+	}
+	if decoded != val {
+		return buf, fmt.Errorf(`[gord] %#v decodes as %#v for WalSet; use a codec that preserves values`, val, decoded)
+	}
+
+	start := len(buf)
+	buf = append(buf, make([]byte, walHeaderSize)...)
+	buf = append(buf, op)
+	buf = append(buf, body...)
+
+	payload := buf[start+walHeaderSize:]
+	binary.LittleEndian.PutUint32(buf[start:], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(buf[start+4:], crc32.Checksum(payload, walTable))
+	return buf, nil
+}
+
+func (self *WalSet) replay() error {
+	info, err := self.file.Stat()
+	if err != nil {
+		return err
+	}
+	reader := bufio.NewReader(self.file)
+
+	head := make([]byte, len(walMagic))
+	size, err := io.ReadFull(reader, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return err
+	}
+	if !bytes.HasPrefix([]byte(walMagic), head[:size]) {
+		return fmt.Errorf(`[gord] %q is not a WalSet log`, self.path)
+	}
+
+	// New file, or a crash while creating it.
+	if size < len(walMagic) {
+		return self.reset()
+	}
+
+	offset := int64(len(walMagic))
+	for {
+		size, op, body, err := walRead(reader)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if errors.Is(err, errWalTorn) {
+			return self.truncate(offset)
+		}
+		if errors.Is(err, errWalBad) {
+			return self.corrupted(offset, size, info.Size())
+		}
+		if err != nil {
+			return err
+		}
+
+		val, err := self.codec().Decode(body)
+		if err == nil && op != walAdd && op != walFirst && op != walLast && op != walDelete {
+			err = fmt.Errorf(`unknown operation %q`, op)
+		}
+		if err != nil {
+			return fmt.Errorf(`[gord] unable to replay WalSet record at offset %v in %q: %w`, offset, self.path, err)
+		}
+
+		walApply(&self.set, op, val)
+		self.records++
+		offset += size
+	}
+}
+
+// Reads one record, reporting incomplete ones as `errWalTorn`. Complete records
+// with an invalid length or checksum are reported as `errWalBad`, along with
+// their size, which for an invalid length is the size they claim.
+func walRead(reader io.Reader) (int64, byte, []byte, error) {
+	var head [walHeaderSize]byte
+	_, err := io.ReadFull(reader, head[:])
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return 0, 0, nil, errWalTorn
+	}
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	size := binary.LittleEndian.Uint32(head[:])
+	if size == 0 || size > walMaxPayload {
+		return int64(walHeaderSize + size), 0, nil, errWalBad
+	}
+
+	payload := make([]byte, size)
+	_, err = io.ReadFull(reader, payload)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return 0, 0, nil, errWalTorn
+	}
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	if crc32.Checksum(payload, walTable) != binary.LittleEndian.Uint32(head[4:]) {
+		return int64(walHeaderSize + size), 0, nil, errWalBad
+	}
+	return int64(walHeaderSize + size), payload[0], payload[1:], nil
+}
+
+func walApply(set *LinkedSet, op byte, val interface{}) {
+	switch op {
+	case walAdd:
+		set.Add(val)
+	case walFirst:
+		set.AddFirst(val)
+	case walLast:
+		set.AddLast(val)
+	case walDelete:
+		set.Delete(val)
+	}
+}
+
+func (self *WalSet) reset() error {
+	err := self.file.Truncate(0)
+	if err != nil {
+		return err
+	}
+	_, err = self.file.WriteString(walMagic)
+	if err != nil {
+		return err
+	}
+	err = self.file.Sync()
+	if err != nil {
+		return err
+	}
+	walSyncDir(self.path)
+	return nil
+}
+
+/*
+A bad record at the given offset is a torn tail only if nothing follows it:
+either it reaches the end of the file, or the rest of the file is zeros, which
+some file systems leave after a crash while appending. Otherwise truncating
+would delete intact records, so this reports an error instead.
+*/
+func (self *WalSet) corrupted(offset, size, end int64) error {
+	if offset+size >= end {
+		return self.truncate(offset)
+	}
+
+	zeros, err := walZeros(io.NewSectionReader(self.file, offset, end-offset))
+	if err != nil {
+		return err
+	}
+	if zeros {
+		return self.truncate(offset)
+	}
+	return fmt.Errorf(`[gord] corrupted WalSet record at offset %v in %q, followed by %v more bytes; refusing to truncate the log`, offset, self.path, end-offset-size)
+}
+
+func walZeros(reader io.Reader) (bool, error) {
+	buf := make([]byte, 4096)
+	for {
+		size, err := reader.Read(buf)
+		for _, char := range buf[:size] {
+			if char != 0 {
+				return false, nil
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+}
+
+func (self *WalSet) truncate(offset int64) error {
+	err := self.file.Truncate(offset)
+	if err != nil {
+		return err
+	}
+	return self.file.Sync()
+}
+
+func (self *WalSet) compact() error {
+	if self.err != nil {
+		return self.err
+	}
+	if self.file == nil {
+		return errWalClosed
+	}
+
+	temp := self.path + `.tmp`
+	file, err := self.snapshot(temp)
+	if err != nil {
+		_ = os.Remove(temp)
+		return fmt.Errorf(`[gord] unable to compact WalSet: %w`, err)
+	}
+
+	err = os.Rename(temp, self.path)
+	if err != nil {
+		_ = file.Close()
+		_ = os.Remove(temp)
+		return fmt.Errorf(`[gord] unable to compact WalSet: %w`, err)
+	}
+
+	_ = self.file.Close()
+	self.file = file
+	self.records = self.set.Len()
+	walSyncDir(self.path)
+	return nil
+}
+
+// Writes the current values to a new log, fsyncs it and returns it open for
+// appending.
+func (self *WalSet) snapshot(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC|os.O_APPEND, 0o666)
+	if err != nil {
+		return nil, err
+	}
+
+	err = self.writeSnapshot(file)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+func (self *WalSet) writeSnapshot(file *os.File) error {
+	writer := bufio.NewWriter(file)
+	_, err := writer.WriteString(walMagic)
+	if err != nil {
+		return err
+	}
+
+	for elem := self.set.ord.Front(); elem != nil; elem = elem.Next() {
+		self.buf, err = self.record(self.buf[:0], walAdd, elem.Value)
+		if err != nil {
+			return err
+		}
+		_, err = writer.Write(self.buf)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = writer.Flush()
+	if err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// Records the error as sticky.
+func (self *WalSet) failed(err error) error {
+	if err != nil && self.err == nil {
+		self.err = err
+	}
+	return err
+}
+
+func (self *WalSet) codec() Codec {
+	if self.opts.Codec != nil {
+		return self.opts.Codec
+	}
+	return JSONCodec{}
+}
+
+// Makes file creation and renaming durable. Best effort: not every platform
+// supports syncing directories.
+func walSyncDir(path string) {
+	dir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return
+	}
+	_ = dir.Sync()
+	_ = dir.Close()
+}
@@ -0,0 +1,379 @@
+package gord
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+/*
+Implement `sql.Scanner`. Replaces the set's contents with the elements of a
+Postgres array literal such as `{a,b,"c d",NULL}`, or of a JSON array such as
+`["a","b","c d",null]`. Duplicates are discarded, keeping the first occurrence,
+which preserves the order of the column. Postgres array elements always become
+strings, even if they look like numbers. JSON elements are decoded as by
+`json.Unmarshal`, so every JSON number becomes a `float64`; nested objects and
+arrays aren't comparable, and are rejected. `NULL` elements become `nil`.
+
+On error, the set keeps its previous contents.
+
+A SQL `NULL` (or JSON `null`) empties the set. A `LinkedSet` can't represent
+`NULL`: `.Value` of a non-nil set always produces an array, so a `NULL` column
+scanned into a `LinkedSet` is written back as `{}`. This includes a `LinkedSet`
+stored by value in a struct field. To preserve `NULL`, scan into a field of
+type `*LinkedSet`, which `database/sql` sets to nil for `NULL`, or use
+`SliceSet`, which represents `NULL` as a nil slice.
+*/
+func (self *LinkedSet) Scan(src interface{}) error {
+	vals, err := scanValues(src)
+	if err != nil {
+		return err
+	}
+	self.clear()
+	for _, val := range vals {
+		self.Add(val)
+	}
+	return nil
+}
+
+/*
+Implement `driver.Valuer`. Encodes the set as a Postgres array literal such as
+`{a,b,"c d",NULL}`. A nil set produces SQL `NULL`; an empty set produces `{}`.
+Elements are converted to text: strings as-is, `nil` as `NULL`, `time.Time` in
+RFC 3339 format, `driver.Valuer` via its value, other types via `fmt.Sprint`.
+*/
+func (self *LinkedSet) Value() (driver.Value, error) {
+	if self == nil {
+		return nil, nil
+	}
+	return valuePgArray(self.Values())
+}
+
+// Concurrency-safe version of `LinkedSet.Scan`. Replaces the contents
+// atomically.
+func (self *SyncLinkedSet) Scan(src interface{}) error {
+	vals, err := scanValues(src)
+	if err != nil {
+		return err
+	}
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.set.clear()
+	for _, val := range vals {
+		self.set.Add(val)
+	}
+	return nil
+}
+
+// Concurrency-safe version of `LinkedSet.Value`.
+func (self *SyncLinkedSet) Value() (driver.Value, error) {
+	if self == nil {
+		return nil, nil
+	}
+	return valuePgArray(self.Values())
+}
+
+// Same as `LinkedSet.Scan`, but a SQL `NULL` (or JSON `null`) makes the slice
+// nil, while an empty array makes it empty and non-nil.
+func (self *SliceSet) Scan(src interface{}) error {
+	vals, err := scanValues(src)
+	if err != nil {
+		return err
+	}
+	self.scanned(vals)
+	return nil
+}
+
+// Same as `LinkedSet.Value`, but a nil slice also produces SQL `NULL`, which
+// allows `NULL` to survive a round trip through `.Scan`.
+func (self *SliceSet) Value() (driver.Value, error) {
+	if self == nil || *self == nil {
+		return nil, nil
+	}
+	return valuePgArray(*self)
+}
+
+// Concurrency-safe version of `SliceSet.Scan`. Replaces the contents
+// atomically.
+func (self *SyncSliceSet) Scan(src interface{}) error {
+	vals, err := scanValues(src)
+	if err != nil {
+		return err
+	}
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.set.scanned(vals)
+	self.mutated(true)
+	return nil
+}
+
+// Concurrency-safe version of `SliceSet.Value`. Like `LinkedSet.Value`, a
+// non-nil set always produces an array, even after scanning `NULL`.
+func (self *SyncSliceSet) Value() (driver.Value, error) {
+	if self == nil {
+		return nil, nil
+	}
+	return valuePgArray(self.Values())
+}
+
+// Concurrency-safe version of `SliceSet.Scan`. Publishes the new contents
+// atomically.
+func (self *CowSliceSet) Scan(src interface{}) error {
+	vals, err := scanValues(src)
+	if err != nil {
+		return err
+	}
+
+	var set SliceSet
+	set.scanned(vals)
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.publish(set)
+	return nil
+}
+
+// Concurrency-safe version of `SliceSet.Value`. Doesn't lock. Like
+// `LinkedSet.Value`, a non-nil set always produces an array, even after
+// scanning `NULL`.
+func (self *CowSliceSet) Value() (driver.Value, error) {
+	if self == nil {
+		return nil, nil
+	}
+	return valuePgArray(self.load())
+}
+
+// Nil input means SQL `NULL`; see `scanValues`.
+func (self *SliceSet) scanned(vals []interface{}) {
+	if vals == nil {
+		self.truncate(0)
+		*self = nil
+		return
+	}
+
+	*self = self.truncate(0)
+	if *self == nil {
+		*self = SliceSet{}
+	}
+	for _, val := range vals {
+		self.Add(val)
+	}
+}
+
+// Returns nil for SQL `NULL` or JSON `null`, and a non-nil slice for an array,
+// even if empty.
+func scanValues(src interface{}) ([]interface{}, error) {
+	switch src := src.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return parseSqlArray(src)
+	case []byte:
+		return parseSqlArray(string(src))
+	default:
+		return nil, fmt.Errorf(`[gord] unable to scan %T into an ordered set`, src)
+	}
+}
+
+func parseSqlArray(src string) ([]interface{}, error) {
+	text := strings.TrimSpace(src)
+
+	if strings.HasPrefix(text, `[`) || text == `null` {
+		var vals []interface{}
+		err := json.Unmarshal([]byte(text), &vals)
+		if err != nil {
+			return nil, fmt.Errorf(`[gord] unable to scan JSON into an ordered set: %w`, err)
+		}
+		for i, val := range vals {
+			if isJSONComposite(val) {
+				return nil, fmt.Errorf(`[gord] unable to scan JSON into an ordered set: element at index %v is an object or array, which isn't comparable`, i)
+			}
+		}
+		return vals, nil
+	}
+
+	vals, err := parsePgArray(text)
+	if err != nil {
+		return nil, fmt.Errorf(`[gord] unable to scan %q into an ordered set: %w`, src, err)
+	}
+	return vals, nil
+}
+
+// Parses a one-dimensional Postgres array literal. See
+// https://www.postgresql.org/docs/current/arrays.html#ARRAYS-IO. Like
+// Postgres, keeps whitespace inside unquoted elements, trimming it only around
+// them, unless escaped.
+func parsePgArray(src string) ([]interface{}, error) {
+	if !strings.HasPrefix(src, `{`) || !strings.HasSuffix(src, `}`) {
+		return nil, fmt.Errorf(`expected an array literal enclosed in {}`)
+	}
+
+	body := src[1 : len(src)-1]
+	if strings.TrimSpace(body) == `` {
+		return []interface{}{}, nil
+	}
+
+	var out []interface{}
+	var buf []byte
+
+	for pos := 0; ; {
+		for pos < len(body) && isPgSpace(body[pos]) {
+			pos++
+		}
+		if pos >= len(body) {
+			return nil, fmt.Errorf(`unexpected end of input, expected element`)
+		}
+
+		buf = buf[:0]
+		quoted := body[pos] == '"'
+		closed := false
+		escaped := false
+
+		// Length of `buf` without trailing unescaped whitespace.
+		size := 0
+		if quoted {
+			pos++
+		}
+
+		for ; pos < len(body); pos++ {
+			char := body[pos]
+			if char == '\\' {
+				pos++
+				if pos >= len(body) {
+					return nil, fmt.Errorf(`unexpected end of input after backslash`)
+				}
+				buf = append(buf, body[pos])
+				size = len(buf)
+				escaped = true
+				continue
+			}
+			if quoted && char == '"' {
+				pos++
+				closed = true
+				break
+			}
+			if !quoted && char == ',' {
+				break
+			}
+			if !quoted && (char == '"' || char == '{' || char == '}') {
+				return nil, fmt.Errorf(`unexpected %q at position %v; nested arrays are not supported`, char, pos+1)
+			}
+			buf = append(buf, char)
+			if quoted || !isPgSpace(char) {
+				size = len(buf)
+			}
+		}
+
+		if quoted && !closed {
+			return nil, fmt.Errorf(`unterminated quoted element`)
+		}
+
+		text := string(buf[:size])
+		if !quoted && !escaped && strings.EqualFold(text, `NULL`) {
+			out = append(out, nil)
+		} else {
+			out = append(out, text)
+		}
+
+		for pos < len(body) && isPgSpace(body[pos]) {
+			pos++
+		}
+		if pos >= len(body) {
+			return out, nil
+		}
+		if body[pos] != ',' {
+			return nil, fmt.Errorf(`unexpected %q at position %v, expected ","`, body[pos], pos+1)
+		}
+		pos++
+	}
+}
+
+func isPgSpace(char byte) bool {
+	return char == ' ' || char == '\t' || char == '\n' || char == '\r' || char == '\v' || char == '\f'
+}
+
+func valuePgArray(vals []interface{}) (driver.Value, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+
+	for i, val := range vals {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		text, null, err := pgArrayElem(val)
+		if err != nil {
+			return nil, err
+		}
+		if null {
+			buf.WriteString(`NULL`)
+			continue
+		}
+		writePgArrayElem(&buf, text)
+	}
+
+	buf.WriteByte('}')
+	return buf.String(), nil
+}
+
+func pgArrayElem(val interface{}) (string, bool, error) {
+	if valuer, ok := val.(driver.Valuer); ok {
+		out, err := valuer.Value()
+		if err != nil {
+			return ``, false, err
+		}
+		val = out
+	}
+
+	switch val := val.(type) {
+	case nil:
+		return ``, true, nil
+	case string:
+		return val, false, nil
+	case []byte:
+		return string(val), false, nil
+	case time.Time:
+		return val.Format(time.RFC3339Nano), false, nil
+	default:
+		return fmt.Sprint(val), false, nil
+	}
+}
+
+func writePgArrayElem(buf *bytes.Buffer, text string) {
+	if !pgArrayElemNeedsQuotes(text) {
+		buf.WriteString(text)
+		return
+	}
+
+	buf.WriteByte('"')
+	for i := 0; i < len(text); i++ {
+		char := text[i]
+		if char == '"' || char == '\\' {
+			buf.WriteByte('\\')
+		}
+		buf.WriteByte(char)
+	}
+	buf.WriteByte('"')
+}
+
+func pgArrayElemNeedsQuotes(text string) bool {
+	if text == `` || strings.EqualFold(text, `NULL`) {
+		return true
+	}
+	for i := 0; i < len(text); i++ {
+		switch char := text[i]; char {
+		case '{', '}', ',', '"', '\\':
+			return true
+		default:
+			if isPgSpace(char) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,261 @@
+package gord
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+/*
+Snapshot of a set's size, approximate memory usage, and contention, returned by
+the `.Stats` method of every type in this package. Byte counts are estimates
+of the memory held by the set's own data structures, based on the layout of
+the current Go runtime; they exclude memory referenced by the values, such as
+the contents of strings or the targets of pointers.
+*/
+type Stats struct {
+	// Number of values, same as `.Len`.
+	Len int
+
+	// Number of values the set can hold without growing. For slice-backed sets,
+	// this is the slice capacity; for other sets, same as `Len`.
+	Cap int
+
+	// Approximate bytes held by hash maps, including empty slots.
+	MapBytes int
+
+	// Approximate bytes held by list nodes and other per-value allocations.
+	NodeBytes int
+
+	// Bytes held by slice backing arrays, including unused capacity.
+	SliceBytes int
+
+	// For concurrency-safe types: number of times a caller had to wait for the
+	// lock, and the total time spent waiting, since the set was created.
+	LockWaits    uint64
+	LockWaitTime time.Duration
+
+	// For `CountingSet`: counts of mutating operations.
+	Ops OpStats
+}
+
+// Implemented by every type in this package. See `Stats`.
+type StatsSet interface{ Stats() Stats }
+
+// Total approximate bytes: `MapBytes + NodeBytes + SliceBytes`.
+func (self Stats) Bytes() int {
+	return self.MapBytes + self.NodeBytes + self.SliceBytes
+}
+
+// Satisfy `StatsSet`.
+func (self *LinkedSet) Stats() Stats {
+	size := self.Len()
+	return Stats{
+		Len:       size,
+		Cap:       size,
+		MapBytes:  mapBytes(size, sizeofAny, sizeofPtr),
+		NodeBytes: size * sizeofElem,
+	}
+}
+
+// Concurrency-safe version of `LinkedSet.Stats`, with lock contention counters.
+func (self *SyncLinkedSet) Stats() Stats {
+	if self == nil {
+		return Stats{}
+	}
+	self.lock.Lock()
+	out := self.set.Stats()
+	self.lock.Unlock()
+	self.lock.stats(&out)
+	return out
+}
+
+// Satisfy `StatsSet`.
+func (self *SliceSet) Stats() Stats {
+	if self == nil {
+		return Stats{}
+	}
+	return Stats{
+		Len:        len(*self),
+		Cap:        cap(*self),
+		SliceBytes: cap(*self) * sizeofAny,
+	}
+}
+
+// Concurrency-safe version of `SliceSet.Stats`, with lock contention counters.
+func (self *SyncSliceSet) Stats() Stats {
+	if self == nil {
+		return Stats{}
+	}
+	self.lock.Lock()
+	out := self.set.Stats()
+	self.lock.Unlock()
+	self.lock.stats(&out)
+	return out
+}
+
+// Concurrency-safe version of `SliceSet.Stats`. Doesn't lock. Lock contention
+// counters apply only to writers.
+func (self *CowSliceSet) Stats() Stats {
+	if self == nil {
+		return Stats{}
+	}
+	set := self.load()
+	out := set.Stats()
+	self.lock.stats(&out)
+	return out
+}
+
+// Satisfy `StatsSet`.
+func (self *TypedLinkedSet[T]) Stats() Stats {
+	size := self.Len()
+	return Stats{
+		Len:       size,
+		Cap:       size,
+		MapBytes:  mapBytes(size, int(unsafe.Sizeof(*new(T))), sizeofPtr),
+		NodeBytes: size * int(unsafe.Sizeof(typedNode[T]{})),
+	}
+}
+
+// Satisfy `StatsSet`. Includes the map of counts.
+func (self *OrdBag) Stats() Stats {
+	if self == nil {
+		return Stats{}
+	}
+	out := self.set.Stats()
+	out.MapBytes += mapBytes(out.Len, sizeofAny, int(unsafe.Sizeof(0)))
+	return out
+}
+
+// Concurrency-safe version of `OrdBag.Stats`, with lock contention counters.
+func (self *SyncOrdBag) Stats() Stats {
+	if self == nil {
+		return Stats{}
+	}
+	self.lock.Lock()
+	out := self.bag.Stats()
+	self.lock.Unlock()
+	self.lock.stats(&out)
+	return out
+}
+
+// Satisfy `StatsSet`. Counts expired values that haven't been removed yet.
+func (self *TTLSet) Stats() Stats {
+	if self == nil {
+		return Stats{}
+	}
+	self.lock.Lock()
+	size := len(self.set)
+	self.lock.Unlock()
+
+	out := Stats{
+		Len:       size,
+		Cap:       size,
+		MapBytes:  mapBytes(size, sizeofAny, sizeofPtr),
+		NodeBytes: size * (sizeofElem + int(unsafe.Sizeof(ttlEntry{}))),
+	}
+	self.lock.stats(&out)
+	return out
+}
+
+// Satisfy `StatsSet`.
+func (self *FreqSet) Stats() Stats {
+	size := self.Len()
+	buckets := 0
+	if self != nil {
+		buckets = self.buckets.Len()
+	}
+	return Stats{
+		Len:      size,
+		Cap:      size,
+		MapBytes: mapBytes(size, sizeofAny, sizeofPtr),
+		NodeBytes: size*(sizeofElem+int(unsafe.Sizeof(freqEntry{}))) +
+			buckets*(sizeofElem+int(unsafe.Sizeof(freqBucket{}))),
+	}
+}
+
+// Satisfy `StatsSet`. Estimates skip list links from their expected number per
+// node, 4/3, instead of counting them.
+func (self *ScoredSet) Stats() Stats {
+	size := self.Len()
+	return Stats{
+		Len:      size,
+		Cap:      size,
+		MapBytes: mapBytes(size, sizeofAny, sizeofPtr),
+		NodeBytes: size*int(unsafe.Sizeof(scoredNode{})) +
+			size*4/3*int(unsafe.Sizeof(scoredLink{})),
+	}
+}
+
+var (
+	sizeofAny  = int(unsafe.Sizeof(*new(interface{})))
+	sizeofPtr  = int(unsafe.Sizeof(uintptr(0)))
+	sizeofElem = int(unsafe.Sizeof(list.Element{}))
+)
+
+/*
+Estimates the memory held by a map with the given number of entries. Go maps
+store entries in groups of 8 slots, with a byte of metadata per slot, and grow
+by doubling when they're about 80% full. This ignores the map header and
+overflow groups.
+*/
+func mapBytes(count, keySize, valSize int) int {
+	if count == 0 {
+		return 0
+	}
+	groups := 1
+	for groups*8*4/5 < count {
+		groups *= 2
+	}
+	return groups * 8 * (1 + keySize + valSize)
+}
+
+// Mutex that counts how often, and for how long, callers wait for it. The
+// uncontended path costs the same as `sync.Mutex.Lock`: a single
+// compare-and-swap.
+type statMutex struct {
+	sync.Mutex
+	debug     debugState
+	waits     atomicUint64
+	waitNanos atomicUint64
+}
+
+func (self *statMutex) Lock() {
+	if !self.Mutex.TryLock() {
+		start := time.Now()
+		self.Mutex.Lock()
+		self.waits.add(1)
+		self.waitNanos.add(uint64(time.Since(start)))
+	}
+	if debugMode {
+		self.debugLocked()
+	}
+}
+
+func (self *statMutex) stats(out *Stats) {
+	out.LockWaits = self.waits.load()
+	out.LockWaitTime = time.Duration(self.waitNanos.load())
+}
+
+/*
+64-bit counter safe for atomic operations at any offset in any enclosing
+struct. On 32-bit platforms, 64-bit atomics require 8-byte alignment, which Go
+guarantees only for the first word of an allocated struct, while `statMutex`
+and `CountingSet` may be embedded at any offset, including in callers' own
+structs. Three 32-bit words always contain an aligned 64-bit pair, like the
+state of `sync.WaitGroup` before Go 1.20. `atomic.Uint64` would require Go
+1.19.
+*/
+type atomicUint64 struct{ words [3]uint32 }
+
+func (self *atomicUint64) ptr() *uint64 {
+	if uintptr(unsafe.Pointer(&self.words))%8 == 0 {
+		return (*uint64)(unsafe.Pointer(&self.words[0]))
+	}
+	return (*uint64)(unsafe.Pointer(&self.words[1]))
+}
+
+func (self *atomicUint64) add(delta uint64) { atomic.AddUint64(self.ptr(), delta) }
+func (self *atomicUint64) load() uint64     { return atomic.LoadUint64(self.ptr()) }
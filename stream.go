@@ -0,0 +1,246 @@
+package gord
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+/*
+Returned, wrapped, by `StreamFormat.Decode` in strict mode when a value is
+already in the set. Use `errors.Is` to check for it.
+*/
+var ErrDuplicate = errors.New(`[gord] duplicate value`)
+
+/*
+Describes the streaming representation used by `Encode` and `Decode`. Values
+are encoded one at a time, in order, via `Codec`, which defaults to
+`JSONCodec`. By default, each value is followed by a newline; with the default
+codec, this is newline-delimited JSON. When decoding, blank lines are skipped,
+and a trailing "\r" is ignored. Accordingly, encoding fails for values whose
+encoding is empty, contains a newline, or ends with "\r". Lines are limited to
+the same length as binary values. With `Binary`, each value is instead
+preceded by its length as a uvarint, which works with any codec.
+
+Neither direction holds the full set of encoded values in memory. Encoding
+`LinkedSet`, `SliceSet`, their concurrency-safe versions and `WalSet` doesn't
+allocate a slice of values either; the concurrency-safe sets, except
+`CowSliceSet`, stay locked until encoding finishes. Other sets are encoded
+from `.Values`.
+*/
+type StreamFormat struct {
+	Codec  Codec
+	Binary bool
+
+	// If true, `.Decode` fails on the first value already present in the set,
+	// including values decoded earlier from the same stream, with an error
+	// wrapping `ErrDuplicate`. Otherwise such values are skipped, keeping their
+	// original position.
+	Strict bool
+}
+
+// Shortcut for `StreamFormat{}.Encode`: newline-delimited JSON.
+func Encode(out io.Writer, set OrdSet) error {
+	return StreamFormat{}.Encode(out, set)
+}
+
+// Shortcut for `StreamFormat{}.Decode`: newline-delimited JSON.
+func Decode(src io.Reader, set OrdSet) error {
+	return StreamFormat{}.Decode(src, set)
+}
+
+// Writes the set's values to the writer in order. See `StreamFormat`. Buffers
+// the output, flushing it before returning.
+func (self StreamFormat) Encode(out io.Writer, set OrdSet) error {
+	if set == nil {
+		return nil
+	}
+
+	writer := bufio.NewWriter(out)
+	index := 0
+	var err error
+
+	eachValue(set, func(val interface{}) bool {
+		err = self.write(writer, val)
+		if err != nil {
+			err = fmt.Errorf(`[gord] unable to encode value %#v at index %v: %w`, val, index, err)
+			return false
+		}
+		index++
+		return true
+	})
+
+	if err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+// Reads values from the reader until EOF, adding them to the set in order.
+// See `StreamFormat`. On error, the values decoded before it remain in the set.
+func (self StreamFormat) Decode(src io.Reader, set OrdSet) error {
+	reader := bufio.NewReader(src)
+	codec := self.codec()
+
+	for index := 0; ; index++ {
+		body, err := self.read(reader)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf(`[gord] unable to read value at index %v: %w`, index, err)
+		}
+
+		val, err := codec.Decode(body)
+		if err != nil {
+			return fmt.Errorf(`[gord] unable to decode value at index %v: %w`, index, err)
+		}
+
+		if !set.Added(val) && self.Strict {
+			return fmt.Errorf(`%w %#v at index %v`, ErrDuplicate, val, index)
+		}
+	}
+}
+
+// Same limit as for `WalSet` records, to avoid huge allocations when decoding
+// a corrupted length.
+const streamMaxLen = walMaxPayload
+
+func (self StreamFormat) write(out *bufio.Writer, val interface{}) error {
+	body, err := self.codec().Encode(val)
+	if err != nil {
+		return err
+	}
+
+	if self.Binary {
+		var head [binary.MaxVarintLen64]byte
+		_, err = out.Write(head[:binary.PutUvarint(head[:], uint64(len(body)))])
+		if err != nil {
+			return err
+		}
+		_, err = out.Write(body)
+		return err
+	}
+
+	switch {
+	case len(body) == 0:
+		return fmt.Errorf(`encoded value is empty; use the binary format`)
+	case bytes.IndexByte(body, '\n') >= 0:
+		return fmt.Errorf(`encoded value contains a newline; use the binary format`)
+	case body[len(body)-1] == '\r':
+		return fmt.Errorf(`encoded value ends with "\r"; use the binary format`)
+	}
+	_, err = out.Write(body)
+	if err != nil {
+		return err
+	}
+	return out.WriteByte('\n')
+}
+
+// Returns `io.EOF` only at the boundary between values.
+func (self StreamFormat) read(src *bufio.Reader) ([]byte, error) {
+	if self.Binary {
+		size, err := binary.ReadUvarint(src)
+		if err != nil {
+			return nil, err
+		}
+		if size > streamMaxLen {
+			return nil, fmt.Errorf(`value length %v exceeds the limit %v`, size, streamMaxLen)
+		}
+
+		body := make([]byte, size)
+		_, err = io.ReadFull(src, body)
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return body, err
+	}
+
+	for {
+		line, err := readLine(src, streamMaxLen+2)
+		line = bytes.TrimSuffix(bytes.TrimSuffix(line, []byte("\n")), []byte("\r"))
+		if len(line) > streamMaxLen {
+			return nil, fmt.Errorf(`line length exceeds the limit %v`, streamMaxLen)
+		}
+		if len(line) > 0 {
+			return line, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Same as `bufio.Reader.ReadBytes('\n')`, but stops after `limit` bytes, to
+// avoid huge allocations when decoding a stream without newlines.
+func readLine(src *bufio.Reader, limit int) ([]byte, error) {
+	var out []byte
+	for {
+		chunk, err := src.ReadSlice('\n')
+		out = append(out, chunk...)
+		if !errors.Is(err, bufio.ErrBufferFull) {
+			return out, err
+		}
+		if len(out) > limit {
+			return out, nil
+		}
+	}
+}
+
+func (self StreamFormat) codec() Codec {
+	if self.Codec != nil {
+		return self.Codec
+	}
+	return JSONCodec{}
+}
+
+// Iterates over the values in order. Avoids copying the values for the sets in
+// this package that support it, holding their locks throughout.
+func eachValue(set OrdSet, fun func(interface{}) bool) {
+	switch set := set.(type) {
+	case *LinkedSet:
+		if set != nil {
+			set.eachWhile(fun)
+		}
+
+	case *SyncLinkedSet:
+		if set != nil {
+			set.lock.Lock()
+			defer set.lock.Unlock()
+			set.set.eachWhile(fun)
+		}
+
+	case *SliceSet:
+		if set != nil {
+			set.eachWhile(fun)
+		}
+
+	case *SyncSliceSet:
+		if set != nil {
+			set.lock.Lock()
+			defer set.lock.Unlock()
+			set.set.eachWhile(fun)
+		}
+
+	case *CowSliceSet:
+		vals := set.load()
+		vals.eachWhile(fun)
+
+	case *WalSet:
+		if set != nil {
+			set.lock.Lock()
+			defer set.lock.Unlock()
+			set.set.eachWhile(fun)
+		}
+
+	default:
+		for _, val := range set.Values() {
+			if !fun(val) {
+				return
+			}
+		}
+	}
+}
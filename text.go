@@ -0,0 +1,434 @@
+package gord
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+/*
+Describes the text representation used by `.Set`, `.MarshalText` and
+`.UnmarshalText` of every type in this package, and by `TextSet`. Values are
+separated by `Sep`, which defaults to ','. Values that are empty, contain `Sep`,
+`Quote` or newlines, or have leading or trailing whitespace, are enclosed in
+`Quote`, which defaults to '"'. Quotes inside quoted values are doubled, as in
+CSV. For example, with the default format, the values `a`, `b c`, ` d`, `e,f`
+and `g"h` are represented as:
+
+	a,b c," d","e,f","g""h"
+
+When parsing, whitespace around values is ignored, and empty unquoted values
+are skipped, which allows "a, b" and "a,,b," as well as an empty string.
+Non-string values are converted to text via `fmt.Sprint`; parsing always
+produces strings.
+*/
+type TextFormat struct {
+	Sep   rune
+	Quote rune
+}
+
+// Parses the text into a list of values. See `TextFormat` for the syntax.
+func (self TextFormat) Split(src string) ([]string, error) {
+	sep, quote, err := self.runes()
+	if err != nil {
+		return nil, err
+	}
+
+	var out []string
+	var buf strings.Builder
+	pos := 0
+
+	for {
+		pos = skipSpace(src, pos, sep)
+		if pos >= len(src) {
+			return out, nil
+		}
+
+		char, size := utf8.DecodeRuneInString(src[pos:])
+		if char == sep {
+			pos += size
+			continue
+		}
+
+		if char != quote {
+			end := strings.IndexRune(src[pos:], sep)
+			if end < 0 {
+				end = len(src)
+			} else {
+				end += pos
+			}
+
+			val := strings.TrimRightFunc(src[pos:end], unicode.IsSpace)
+			if index := strings.IndexRune(val, quote); index >= 0 {
+				return nil, fmt.Errorf(`[gord] unexpected quote at position %v in unquoted value`, pos+index+1)
+			}
+
+			out = append(out, val)
+			pos = end
+			continue
+		}
+
+		start := pos
+		pos += size
+		buf.Reset()
+		closed := false
+
+		for pos < len(src) {
+			char, size := utf8.DecodeRuneInString(src[pos:])
+			pos += size
+			if char != quote {
+				buf.WriteRune(char)
+				continue
+			}
+
+			next, nextSize := utf8.DecodeRuneInString(src[pos:])
+			if pos < len(src) && next == quote {
+				buf.WriteRune(quote)
+				pos += nextSize
+				continue
+			}
+
+			closed = true
+			break
+		}
+
+		if !closed {
+			return nil, fmt.Errorf(`[gord] unterminated quoted value at position %v`, start+1)
+		}
+		out = append(out, buf.String())
+
+		pos = skipSpace(src, pos, sep)
+		if pos < len(src) {
+			char, size := utf8.DecodeRuneInString(src[pos:])
+			if char != sep {
+				return nil, fmt.Errorf(`[gord] unexpected %q at position %v after quoted value`, char, pos+1)
+			}
+			pos += size
+		}
+	}
+}
+
+// Formats the values as text. See `TextFormat` for the syntax.
+func (self TextFormat) Join(vals []interface{}) (string, error) {
+	sep, quote, err := self.runes()
+	if err != nil {
+		return ``, err
+	}
+
+	var buf strings.Builder
+	for i, val := range vals {
+		if i > 0 {
+			buf.WriteRune(sep)
+		}
+
+		text, ok := val.(string)
+		if !ok {
+			text = fmt.Sprint(val)
+		}
+
+		if !textNeedsQuotes(text, sep, quote) {
+			buf.WriteString(text)
+			continue
+		}
+
+		buf.WriteRune(quote)
+		for _, char := range text {
+			if char == quote {
+				buf.WriteRune(quote)
+			}
+			buf.WriteRune(char)
+		}
+		buf.WriteRune(quote)
+	}
+	return buf.String(), nil
+}
+
+func (self TextFormat) runes() (rune, rune, error) {
+	sep, quote := self.Sep, self.Quote
+	if sep == 0 {
+		sep = ','
+	}
+	if quote == 0 {
+		quote = '"'
+	}
+	if sep == quote {
+		return 0, 0, fmt.Errorf(`[gord] text separator and quote must differ, got %q for both`, sep)
+	}
+	if unicode.IsSpace(quote) {
+		return 0, 0, fmt.Errorf(`[gord] text quote must not be whitespace, got %q`, quote)
+	}
+	return sep, quote, nil
+}
+
+/*
+Adapter that makes any `OrdSet` usable as a `flag.Value`, `encoding.TextMarshaler`
+and `encoding.TextUnmarshaler`, with a custom `TextFormat`. The types in this
+package implement the same methods with the default format. Example:
+
+	tags := gord.TextSet{OrdSet: gord.NewOrdSet(), TextFormat: gord.TextFormat{Sep: ';'}}
+	flag.Var(&tags, `tag`, `tags, repeatable, separated with ";"`)
+*/
+type TextSet struct {
+	OrdSet
+	TextFormat
+}
+
+// Implement `flag.Value`. Adds the parsed values to the set, in order,
+// keeping the existing values, which allows repeating the flag.
+func (self *TextSet) Set(src string) error {
+	return setText(self.OrdSet, self.TextFormat, src)
+}
+
+// Implement `flag.Value`. Returns the set's text representation, or an empty
+// string if the set is missing or the format is invalid.
+func (self *TextSet) String() string {
+	if self == nil || self.OrdSet == nil {
+		return ``
+	}
+	out, _ := self.Join(self.Values())
+	return out
+}
+
+// Implement `encoding.TextMarshaler`.
+func (self *TextSet) MarshalText() ([]byte, error) {
+	return marshalText(self.OrdSet, self.TextFormat)
+}
+
+// Implement `encoding.TextUnmarshaler`. Replaces the set's contents.
+func (self *TextSet) UnmarshalText(src []byte) error {
+	vals, err := self.Split(string(src))
+	if err != nil {
+		return err
+	}
+	for self.Len() > 0 {
+		_, _ = self.PoppedLast()
+	}
+	for _, val := range vals {
+		self.Add(val)
+	}
+	return nil
+}
+
+/*
+Implement `flag.Value`. Parses the text in the default `TextFormat` and adds
+the values to the set, in order, keeping the existing values. This allows
+repeatable flags and comma-separated lists: `-tag a -tag b,c -tag a` produces
+the set `[a b c]`. See `TextSet` for a custom format.
+*/
+func (self *LinkedSet) Set(src string) error {
+	return setText(self, TextFormat{}, src)
+}
+
+// Implement `encoding.TextMarshaler`, using the default `TextFormat`. Unlike
+// `.String`, the output can be parsed back.
+func (self *LinkedSet) MarshalText() ([]byte, error) {
+	return marshalText(self, TextFormat{})
+}
+
+// Implement `encoding.TextUnmarshaler`, using the default `TextFormat`.
+// Replaces the set's contents.
+func (self *LinkedSet) UnmarshalText(src []byte) error {
+	vals, err := TextFormat{}.Split(string(src))
+	if err != nil {
+		return err
+	}
+	self.clear()
+	for _, val := range vals {
+		self.Add(val)
+	}
+	return nil
+}
+
+// Concurrency-safe version of `LinkedSet.Set`. Adds the values atomically.
+func (self *SyncLinkedSet) Set(src string) error {
+	vals, err := TextFormat{}.Split(src)
+	if err != nil {
+		return err
+	}
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	for _, val := range vals {
+		self.set.Add(val)
+	}
+	return nil
+}
+
+// Concurrency-safe version of `LinkedSet.MarshalText`.
+func (self *SyncLinkedSet) MarshalText() ([]byte, error) {
+	return marshalText(self, TextFormat{})
+}
+
+// Concurrency-safe version of `LinkedSet.UnmarshalText`. Replaces the contents
+// atomically.
+func (self *SyncLinkedSet) UnmarshalText(src []byte) error {
+	vals, err := TextFormat{}.Split(string(src))
+	if err != nil {
+		return err
+	}
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.set.clear()
+	for _, val := range vals {
+		self.set.Add(val)
+	}
+	return nil
+}
+
+// Same as `LinkedSet.Set`.
+func (self *SliceSet) Set(src string) error {
+	return setText(self, TextFormat{}, src)
+}
+
+// Same as `LinkedSet.MarshalText`.
+func (self *SliceSet) MarshalText() ([]byte, error) {
+	return marshalText(self, TextFormat{})
+}
+
+// Same as `LinkedSet.UnmarshalText`.
+func (self *SliceSet) UnmarshalText(src []byte) error {
+	vals, err := TextFormat{}.Split(string(src))
+	if err != nil {
+		return err
+	}
+	*self = self.truncate(0)
+	for _, val := range vals {
+		self.Add(val)
+	}
+	return nil
+}
+
+// Concurrency-safe version of `SliceSet.Set`. Adds the values atomically.
+func (self *SyncSliceSet) Set(src string) error {
+	vals, err := TextFormat{}.Split(src)
+	if err != nil {
+		return err
+	}
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	for _, val := range vals {
+		self.mutated(self.set.Added(val))
+	}
+	return nil
+}
+
+// Concurrency-safe version of `SliceSet.MarshalText`.
+func (self *SyncSliceSet) MarshalText() ([]byte, error) {
+	return marshalText(self, TextFormat{})
+}
+
+// Concurrency-safe version of `SliceSet.UnmarshalText`. Replaces the contents
+// atomically.
+func (self *SyncSliceSet) UnmarshalText(src []byte) error {
+	vals, err := TextFormat{}.Split(string(src))
+	if err != nil {
+		return err
+	}
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.set = self.set.truncate(0)
+	for _, val := range vals {
+		self.set.Add(val)
+	}
+	self.mutated(true)
+	return nil
+}
+
+// Concurrency-safe version of `SliceSet.Set`. Publishes the values atomically.
+func (self *CowSliceSet) Set(src string) error {
+	vals, err := TextFormat{}.Split(src)
+	if err != nil {
+		return err
+	}
+	self.write(
+		func(set SliceSet) bool {
+			for _, val := range vals {
+				if !set.Has(val) {
+					return false
+				}
+			}
+			return true
+		},
+		func(set *SliceSet) {
+			for _, val := range vals {
+				set.Add(val)
+			}
+		},
+	)
+	return nil
+}
+
+// Concurrency-safe version of `SliceSet.MarshalText`. Doesn't lock.
+func (self *CowSliceSet) MarshalText() ([]byte, error) {
+	return marshalText(self, TextFormat{})
+}
+
+// Concurrency-safe version of `SliceSet.UnmarshalText`. Publishes the new
+// contents atomically.
+func (self *CowSliceSet) UnmarshalText(src []byte) error {
+	vals, err := TextFormat{}.Split(string(src))
+	if err != nil {
+		return err
+	}
+
+	var set SliceSet
+	for _, val := range vals {
+		set.Add(val)
+	}
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.publish(set)
+	return nil
+}
+
+func setText(set OrdSet, format TextFormat, src string) error {
+	vals, err := format.Split(src)
+	if err != nil {
+		return err
+	}
+	for _, val := range vals {
+		set.Add(val)
+	}
+	return nil
+}
+
+func marshalText(set OrdSet, format TextFormat) ([]byte, error) {
+	if set == nil {
+		return nil, nil
+	}
+	out, err := format.Join(set.Values())
+	return []byte(out), err
+}
+
+// Skips whitespace other than the separator, which might itself be whitespace.
+func skipSpace(src string, pos int, sep rune) int {
+	for pos < len(src) {
+		char, size := utf8.DecodeRuneInString(src[pos:])
+		if char == sep || !unicode.IsSpace(char) {
+			break
+		}
+		pos += size
+	}
+	return pos
+}
+
+func textNeedsQuotes(text string, sep, quote rune) bool {
+	if text == `` {
+		return true
+	}
+
+	first, _ := utf8.DecodeRuneInString(text)
+	last, _ := utf8.DecodeLastRuneInString(text)
+	if unicode.IsSpace(first) || unicode.IsSpace(last) {
+		return true
+	}
+
+	return strings.ContainsRune(text, sep) ||
+		strings.ContainsRune(text, quote) ||
+		strings.ContainsAny(text, "\n\r")
+}
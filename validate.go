@@ -0,0 +1,352 @@
+package gord
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+)
+
+/*
+Returned, wrapped, by the `.Validate` method of every type in this package when
+the set's internal structures are inconsistent. This indicates a bug, a set
+that was copied after mutation, or concurrent mutation of a
+concurrency-unsafe set. Use `errors.Is` to check for it.
+*/
+var ErrInvalid = errors.New(`[gord] invalid set`)
+
+/*
+Checks that the map and the list agree: same size, every list node is
+reachable from the front and indexed in the map under its own value, and no
+value occurs twice. If `.Fingerprint` has been called, also checks the running
+hash. Returns an error wrapping `ErrInvalid`, describing the first
+inconsistency in list order. Takes O(n) time and memory; meant for tests and
+debugging.
+*/
+func (self *LinkedSet) Validate() error {
+	if self == nil {
+		return nil
+	}
+	return self.validate(`*LinkedSet`)
+}
+
+// Concurrency-safe version of `LinkedSet.Validate`.
+func (self *SyncLinkedSet) Validate() error {
+	if self == nil {
+		return nil
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.set.validate(`*SyncLinkedSet`)
+}
+
+// Checks that no value occurs twice. Returns an error wrapping `ErrInvalid`
+// that describes the first duplicate. Takes O(n) time and memory.
+func (self *SliceSet) Validate() error {
+	if self == nil {
+		return nil
+	}
+	return self.validate(`*SliceSet`)
+}
+
+// Concurrency-safe version of `SliceSet.Validate`.
+func (self *SyncSliceSet) Validate() error {
+	if self == nil {
+		return nil
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.set.validate(`*SyncSliceSet`)
+}
+
+// Concurrency-safe version of `SliceSet.Validate`. Doesn't lock.
+func (self *CowSliceSet) Validate() error {
+	set := self.load()
+	return set.validate(`*CowSliceSet`)
+}
+
+// Same as `LinkedSet.Validate`. Also checks that the links in both directions
+// agree.
+func (self *TypedLinkedSet[T]) Validate() error {
+	if self == nil {
+		return nil
+	}
+	const typ = `*TypedLinkedSet`
+
+	index := 0
+	var prev *typedNode[T]
+	for node := self.first; node != nil; node = node.next {
+		if index >= len(self.set) {
+			return invalidf(typ, `list has more nodes than the map has entries (%d)`, len(self.set))
+		}
+		if node.prev != prev {
+			return invalidf(typ, `node at index %d with value %#v has a wrong backward link`, index, node.val)
+		}
+		if self.set[node.val] != node {
+			return invalidf(typ, `value %#v at index %d isn't indexed in the map`, node.val, index)
+		}
+		prev = node
+		index++
+	}
+
+	if index != len(self.set) {
+		return invalidf(typ, `map has %d entries, list has %d nodes`, len(self.set), index)
+	}
+	if self.last != prev {
+		return invalidf(typ, `last node isn't reachable from the first`)
+	}
+	return nil
+}
+
+// Checks the inner set like `LinkedSet.Validate`, then checks that every value
+// has a positive count, and that the counts add up to `.Total`.
+func (self *OrdBag) Validate() error {
+	if self == nil {
+		return nil
+	}
+	return self.validate(`*OrdBag`)
+}
+
+// Concurrency-safe version of `OrdBag.Validate`.
+func (self *SyncOrdBag) Validate() error {
+	if self == nil {
+		return nil
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.bag.validate(`*SyncOrdBag`)
+}
+
+// Same as `LinkedSet.Validate`. Also checks that values are ordered by expiry.
+// Expired values that haven't been removed yet are valid.
+func (self *TTLSet) Validate() error {
+	if self == nil {
+		return nil
+	}
+	const typ = `*TTLSet`
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	err := validateList(typ, self.set, &self.ord, func(elem *list.Element) interface{} {
+		return elem.Value.(ttlEntry).val
+	})
+	if err != nil {
+		return err
+	}
+
+	index := 0
+	for elem := self.ord.Front(); elem != nil && elem.Next() != nil; elem = elem.Next() {
+		entry, next := elem.Value.(ttlEntry), elem.Next().Value.(ttlEntry)
+		if ttlBefore(next.deadline, entry.deadline) {
+			return invalidf(typ, `value %#v at index %d expires before the preceding value %#v`, next.val, index+1, entry.val)
+		}
+		index++
+	}
+	return nil
+}
+
+// Checks that buckets are non-empty and sorted by count, and that every value
+// is indexed in the map and linked to its own bucket.
+func (self *FreqSet) Validate() error {
+	if self == nil {
+		return nil
+	}
+	const typ = `*FreqSet`
+
+	total := 0
+	prevCount := 0
+	for bucket := self.buckets.Front(); bucket != nil; bucket = bucket.Next() {
+		vals := &bucket.Value.(*freqBucket).vals
+		count := bucket.Value.(*freqBucket).count
+
+		if count <= prevCount {
+			return invalidf(typ, `bucket with count %d follows bucket with count %d`, count, prevCount)
+		}
+		if vals.Len() == 0 {
+			return invalidf(typ, `bucket with count %d is empty`, count)
+		}
+
+		for elem := vals.Front(); elem != nil; elem = elem.Next() {
+			entry := elem.Value.(freqEntry)
+			if entry.bucket != bucket {
+				return invalidf(typ, `value %#v in bucket with count %d links to another bucket`, entry.val, count)
+			}
+			if self.set[entry.val] != elem {
+				return invalidf(typ, `value %#v in bucket with count %d isn't indexed in the map`, entry.val, count)
+			}
+			total++
+		}
+		prevCount = count
+	}
+
+	if total != len(self.set) {
+		return invalidf(typ, `map has %d entries, buckets have %d values`, len(self.set), total)
+	}
+	return nil
+}
+
+// Checks the bottom level of the skip list like `LinkedSet.Validate`, plus the
+// order of values, backward links, and the spans of links on every level.
+func (self *ScoredSet) Validate() error {
+	if self.Len() == 0 {
+		return nil
+	}
+	const typ = `*ScoredSet`
+
+	ranks := make(map[*scoredNode]int, len(self.set))
+	var prev *scoredNode
+	for node := self.head.next[0].node; node != nil; node = node.next[0].node {
+		rank := len(ranks)
+		if rank >= len(self.set) {
+			return invalidf(typ, `list has more nodes than the map has entries (%d)`, len(self.set))
+		}
+		if self.set[node.val] != node {
+			return invalidf(typ, `value %#v at rank %d isn't indexed in the map`, node.val, rank)
+		}
+		if node.back != prev {
+			return invalidf(typ, `value %#v at rank %d has a wrong backward link`, node.val, rank)
+		}
+		if prev != nil && !prev.less(node) {
+			return invalidf(typ, `value %#v at rank %d is out of order`, node.val, rank)
+		}
+		ranks[node] = rank + 1
+		prev = node
+	}
+
+	if len(ranks) != len(self.set) {
+		return invalidf(typ, `map has %d entries, list has %d nodes`, len(self.set), len(ranks))
+	}
+	if self.tail != prev {
+		return invalidf(typ, `tail isn't the last node`)
+	}
+
+	for level := 0; level < self.level; level++ {
+		elem, rank := &self.head, 0
+		for link := elem.next[level]; link.node != nil; link = elem.next[level] {
+			if ranks[link.node]-rank != link.span {
+				return invalidf(typ, `link to value %#v on level %d spans %d nodes instead of %d`, link.node.val, level, link.span, ranks[link.node]-rank)
+			}
+			elem, rank = link.node, ranks[link.node]
+		}
+	}
+	return nil
+}
+
+// Validates the inner set, if it has a `.Validate` method.
+func (self *CountingSet) Validate() error {
+	if set, ok := self.OrdSet.(interface{ Validate() error }); ok {
+		return set.Validate()
+	}
+	return nil
+}
+
+func (self *LinkedSet) validate(typ string) error {
+	err := validateList(typ, self.set, &self.ord, listValue)
+	if err != nil {
+		return err
+	}
+
+	if self.hashed {
+		hash := unordHash(self.Values())
+		if hash != self.hash {
+			return invalidf(typ, `running fingerprint is %v, expected %v`, self.hash, hash)
+		}
+	}
+	return nil
+}
+
+func (self SliceSet) validate(typ string) error {
+	indexes := make(map[interface{}]int, len(self))
+	for i, val := range self {
+		prev, ok := indexes[val]
+		if ok {
+			return invalidf(typ, `value %#v at index %d duplicates index %d`, val, i, prev)
+		}
+		indexes[val] = i
+	}
+	return nil
+}
+
+func (self *OrdBag) validate(typ string) error {
+	err := self.set.validate(typ)
+	if err != nil {
+		return err
+	}
+
+	if len(self.counts) != self.set.Len() {
+		return invalidf(typ, `%d values have counts, but the set has %d values`, len(self.counts), self.set.Len())
+	}
+
+	total := 0
+	index := 0
+	for elem := self.set.ord.Front(); elem != nil; elem = elem.Next() {
+		count := self.counts[elem.Value]
+		if count <= 0 {
+			return invalidf(typ, `value %#v at index %d has count %d`, elem.Value, index, count)
+		}
+		total += count
+		index++
+	}
+
+	if total != self.total {
+		return invalidf(typ, `counts add up to %d, but the total is %d`, total, self.total)
+	}
+	return nil
+}
+
+/*
+Shared by sets built on a map and a `list.List`. A `list.List` copied after
+mutation shares nodes with the original, and walking it from the front reaches
+the original's nodes, so this checks reachability against the length recorded
+in the list itself.
+*/
+func validateList(
+	typ string,
+	set map[interface{}]*list.Element,
+	ord *list.List,
+	value func(*list.Element) interface{},
+) error {
+	if len(set) != ord.Len() {
+		return invalidf(typ, `map has %d entries, list has %d nodes`, len(set), ord.Len())
+	}
+
+	indexes := make(map[*list.Element]int, len(set))
+	var last *list.Element
+
+	for elem := ord.Front(); elem != nil; elem = elem.Next() {
+		index := len(indexes)
+		if index >= ord.Len() {
+			return invalidf(typ, `list has more reachable nodes than its length %d; it may have been copied`, ord.Len())
+		}
+
+		val := value(elem)
+		other := set[val]
+		if other == nil {
+			return invalidf(typ, `value %#v at index %d is missing from the map`, val, index)
+		}
+		if other != elem {
+			prev, ok := indexes[other]
+			if ok {
+				return invalidf(typ, `value %#v at index %d duplicates index %d`, val, index, prev)
+			}
+			return invalidf(typ, `value %#v at index %d is indexed in the map under a different node`, val, index)
+		}
+
+		indexes[elem] = index
+		last = elem
+	}
+
+	if len(indexes) != ord.Len() {
+		return invalidf(typ, `list has %d reachable nodes, but its length is %d`, len(indexes), ord.Len())
+	}
+	if ord.Back() != last {
+		return invalidf(typ, `last node isn't reachable from the first; the list may have been copied`)
+	}
+	return nil
+}
+
+func listValue(elem *list.Element) interface{} { return elem.Value }
+
+func invalidf(typ string, format string, args ...interface{}) error {
+	return fmt.Errorf(`%w: %v: %v`, ErrInvalid, typ, fmt.Sprintf(format, args...))
+}